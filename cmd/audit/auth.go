@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/pflag"
+
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+)
+
+// extraHeadersFromFlags collects the headers given with --header and
+// --cookie-file, and the basic auth credentials of the environment variable
+// named by --basic-auth-env. Credentials are never taken from the command
+// line, where they would show up in the process list and shell history.
+// Errors never contain header values.
+func extraHeadersFromFlags(flags *pflag.FlagSet) (map[string]string, error) {
+	headers := map[string]string{}
+
+	values, err := flags.GetStringArray("header")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read value for --header flag")
+	}
+	for _, v := range values {
+		name, value, err := lighthouse.ParseHeader(v)
+		if err != nil {
+			return nil, microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, "header"), err)
+		}
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+
+	cookieFile, err := flags.GetString("cookie-file")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read value for --cookie-file flag")
+	}
+	if cookieFile != "" {
+		cookies, err := lighthouse.ReadCookieFile(cookieFile)
+		if err != nil {
+			return nil, microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, "cookie-file"), err)
+		}
+		if existing, ok := headers["Cookie"]; ok {
+			cookies = existing + "; " + cookies
+		}
+		headers["Cookie"] = cookies
+	}
+
+	envVar, err := flags.GetString("basic-auth-env")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read value for --basic-auth-env flag")
+	}
+	credentials := os.Getenv(envVar)
+	if credentials == "" && flags.Changed("basic-auth-env") {
+		return nil, microerror.Maskf(invalidFlagsError, "%s: environment variable %s is not set", flagRef(flags, "basic-auth-env"), envVar)
+	}
+	if credentials != "" {
+		if _, ok := headers["Authorization"]; ok {
+			return nil, microerror.Maskf(invalidFlagsError, "basic auth conflicts with an Authorization header of %s", flagRef(flags, "header"))
+		}
+		headers["Authorization"], err = lighthouse.BasicAuthHeader(credentials)
+		if err != nil {
+			return nil, microerror.Maskf(invalidFlagsError, "%s: %s", envVar, err)
+		}
+	}
+
+	return headers, nil
+}
@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// cleanup collects functions undoing side effects of the audit command,
+// like started containers. They run in reverse order.
+type cleanup []func()
+
+func (c *cleanup) add(f func()) {
+	*c = append(*c, f)
+}
+
+func (c *cleanup) run() {
+	for i := len(*c) - 1; i >= 0; i-- {
+		(*c)[i]()
+	}
+	*c = nil
+}
+
+// exit runs the cleanup functions and exits with the given code.
+func (c *cleanup) exit(code int) {
+	c.run()
+	os.Exit(code)
+}
+
+// handleSignals cancels the audits on the first signal, so they stop and
+// clean up. The second signal exits immediately, for cleanups that hang.
+func handleSignals(signals <-chan os.Signal, cancel func(), exit func(int)) {
+	sig := <-signals
+	fmt.Printf("Received %s, stopping audits. Repeat it to exit immediately\n", sig)
+	cancel()
+
+	sig = <-signals
+	fmt.Printf("Received %s again, exiting without cleaning up\n", sig)
+	code := 1
+	if s, ok := sig.(syscall.Signal); ok {
+		code = 128 + int(s)
+	}
+	exit(code)
+}
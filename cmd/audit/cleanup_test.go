@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestHandleSignals checks that the first signal cancels the audits and the
+// second exits.
+func TestHandleSignals(t *testing.T) {
+	signals := make(chan os.Signal, 2)
+	cancelled := make(chan bool, 1)
+	exited := make(chan int, 1)
+
+	go handleSignals(signals, func() { cancelled <- true }, func(code int) { exited <- code })
+
+	signals <- syscall.SIGINT
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the first signal to cancel the audits")
+	}
+	select {
+	case code := <-exited:
+		t.Fatalf("expected no exit after the first signal, got %d", code)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	signals <- syscall.SIGTERM
+	select {
+	case code := <-exited:
+		if code != 143 {
+			t.Errorf("expected exit code 143, got %d", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the second signal to exit")
+	}
+}
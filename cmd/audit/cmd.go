@@ -2,14 +2,28 @@
 package audit
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/fatih/color"
 	"github.com/giantswarm/microerror"
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
+	"github.com/giantswarm/lighthouse-keeper/service/config"
+	"github.com/giantswarm/lighthouse-keeper/service/crawler"
+	"github.com/giantswarm/lighthouse-keeper/service/docker"
 	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+	"github.com/giantswarm/lighthouse-keeper/service/output"
+	"github.com/giantswarm/lighthouse-keeper/service/urllist"
 )
 
 // Cmd is our cobra command
@@ -25,84 +39,1080 @@ var Cmd = &cobra.Command{
 
   lighthouse-keeper audit --name mysite --form-factor mobile --url https://example.com/
 
+  lighthouse-keeper audit --name mysite --form-factor both --url https://example.com/
+
+  lighthouse-keeper audit --preset mobile-slow-4g --url https://example.com/
+
+  lighthouse-keeper audit --preset no-throttling,mobile-slow-4g --form-factor both --url https://example.com/
+
+  lighthouse-keeper audit --config lighthouse-keeper.yaml
+
+  LHK_RUNS=5 lighthouse-keeper audit --config lighthouse-keeper.yaml --threshold performance=90
+
+  lighthouse-keeper audit --throttling-method devtools --throttling-rtt-ms 40 \
+    --throttling-throughput-kbps 10240 --screen-width 1920 --screen-height 1080 --url https://example.com/
+
   lighthouse-keeper audit --url https://container:5000/ --docker-link container:container
 
+  lighthouse-keeper audit --url http://container:5000/ --docker-link container:container \
+    --wait-for http://container:5000/health --wait-timeout 60s
+
+  lighthouse-keeper audit --url http://web:8000/ --docker-network myproject_default
+
+  lighthouse-keeper audit --url http://localhost:8000/ --docker-network host
+
+  lighthouse-keeper audit --url http://site.local/ --add-host site.local:172.17.0.1
+
+  lighthouse-keeper audit --serve-image myapp:pr-123 --serve-port 8000 --url / --url /about
+
+  lighthouse-keeper audit --static-dir dist --path / --path /about
+
+  lighthouse-keeper audit --compose-file docker-compose.yml --compose-service web --compose-port 8000 --url /
+
+  lighthouse-keeper audit --url-file urls.csv
+
+  lighthouse-keeper audit --sitemap https://example.com/sitemap.xml --include '/blog/' --max-urls 20
+
+  lighthouse-keeper audit --crawl https://example.com/ --depth 2 --max-pages 50
+
+  lighthouse-keeper audit --output-dir reports --name-template '{{.Date}}/{{.Host}}-{{.PathSlug}}-{{.FormFactor}}' \
+    --url https://example.com/
+
+  lighthouse-keeper audit --output-format json,html,csv --save-assets --url https://example.com/
+
+  lighthouse-keeper audit --only-categories accessibility,seo --url https://example.com/
+
+  lighthouse-keeper audit --config-path ./lighthouse-config.js --url https://example.com/
+
+  lighthouse-keeper audit --runs 5 --url https://example.com/
+
+  lighthouse-keeper audit --timeout 2m --url https://example.com/
+
+  lighthouse-keeper audit --cpus 2 --memory 2g --cpuset-cpus 0-1 --shm-size 1g \
+    --min-benchmark-index 800 --benchmark-fail --retries 2 --url https://example.com/
+
+  lighthouse-keeper audit --retries 2 --retry-backoff 10s --url https://example.com/
+
+  lighthouse-keeper audit --runtime native --url https://example.com/
+
+  lighthouse-keeper audit --image-tag 4.0.0 --pull missing --url https://example.com/
+
+  lighthouse-keeper audit --image-archive lighthouse.tar --pull never --url https://example.com/
+
+  lighthouse-keeper audit --container-user image --url https://example.com/
+
   lighthouse-keeper audit \
     --name first-name --url http://first-url \
-    --name second-name --url http://second-url`,
+    --name second-name --url http://second-url
+
+  lighthouse-keeper audit --concurrency 4 \
+    --url http://first-url --url http://second-url \
+    --url http://third-url --url http://fourth-url`,
 }
 
 func init() {
-	Cmd.Flags().StringArrayP("url", "u", []string{}, "URL to audit, can be used multiple times")
-	Cmd.Flags().StringArrayP("name", "n", []string{}, "Output file name prefix, can be used multiple times")
-	Cmd.Flags().StringP("form-factor", "f", "desktop", "Either 'desktop' or 'mobile")
-	Cmd.Flags().StringArrayP("docker-link", "l", []string{}, "Link the lighthouse docker container to these named links")
-	Cmd.Flags().BoolP("ignore-certificate-errors", "", false, "Ignore certificate errors")
+	defineFlags(Cmd.Flags())
+}
+
+// defineFlags defines the flags of the command. Flag values are resolved
+// into a separate flag set with the same definitions, see resolveFlags.
+func defineFlags(flags *pflag.FlagSet) {
+	flags.StringP("config", "", "", "Read the sites, form factors, presets, runs, auth and thresholds to audit from this "+config.DefaultFile+" file. Flags and "+envPrefix+"* environment variables take precedence")
+	flags.StringArrayP("url", "u", []string{}, "URL to audit, can be used multiple times")
+	flags.StringArrayP("name", "n", []string{}, "Output file name prefix, can be used multiple times")
+	flags.StringP("output-dir", "", ".", "Directory to write the reports and the "+output.ManifestFile+" manifest to")
+	flags.StringP("name-template", "", "", "Template for report names, e.g. '{{.Host}}-{{.PathSlug}}-{{.FormFactor}}'. Fields: Name, Host, PathSlug, FormFactor, Preset, Index, Date, Time, GitSHA")
+	flags.StringSliceP("output-format", "", []string{lighthouse.FormatJSON}, "Report formats to write, comma separated, any of "+strings.Join(lighthouse.Formats, ", ")+". JSON is always written")
+	flags.BoolP("save-assets", "", false, "Also write the trace and devtools log of each run")
+	flags.StringP("form-factor", "f", "desktop", "Form factor to emulate, 'desktop', 'mobile', or 'both' or a comma separated list to audit each URL with several, as <name>-<form factor>.json")
+	flags.StringP("preset", "", "", "Emulation preset, one of "+strings.Join(lighthouse.PresetNames(), ", ")+", or a comma separated list to audit each URL with several, as <name>-<preset>.json")
+	flags.StringP("throttling-method", "", "", "Either 'simulate', 'devtools' or 'provided'")
+	flags.Float64P("throttling-rtt-ms", "", 0, "Network round trip time in milliseconds")
+	flags.Float64P("throttling-throughput-kbps", "", 0, "Network throughput in Kbps")
+	flags.Float64P("throttling-cpu-slowdown", "", 0, "CPU slowdown multiplier")
+	flags.IntP("screen-width", "", 0, "Emulated screen width in pixels")
+	flags.IntP("screen-height", "", 0, "Emulated screen height in pixels")
+	flags.Float64P("screen-dpr", "", 0, "Emulated device pixel ratio")
+	flags.StringArrayP("docker-link", "l", []string{}, "Link the lighthouse docker container to these named links")
+	flags.StringP("docker-network", "", "", "Connect the lighthouse container to this network, or 'host' to use the host network. With --compose-file, one of the networks of the compose file")
+	flags.StringArrayP("add-host", "", []string{}, "Add a host:ip mapping to the lighthouse container, can be used multiple times")
+	flags.BoolP("ignore-certificate-errors", "", false, "Ignore certificate errors")
+	flags.StringArrayP("header", "", []string{}, "Send an extra 'Name: value' header with every request, can be used multiple times")
+	flags.StringP("cookie-file", "", "", "Send the cookies from this Netscape cookies.txt or JSON file")
+	flags.StringP("basic-auth-env", "", basicAuthEnv, "Environment variable holding basic auth credentials as user:password")
+	flags.StringP("url-file", "", "", "Audit the URLs listed in this file, one URL and optional name per line, or as CSV or YAML")
+	flags.StringArrayP("sitemap", "", []string{}, "Audit the URLs listed in this sitemap or sitemap index, can be used multiple times")
+	flags.StringP("crawl", "", "", "Audit the pages found by following same-origin links from this URL")
+	flags.IntP("depth", "", crawler.DefaultDepth, "Maximum number of links --crawl follows from the start URL")
+	flags.IntP("max-pages", "", crawler.DefaultMaxPages, "Maximum number of pages --crawl finds")
+	flags.StringP("include", "", "", "Only audit URLs from --url-file, --sitemap, --crawl or --static-dir matching this regular expression")
+	flags.StringP("exclude", "", "", "Don't audit URLs from --url-file, --sitemap, --crawl or --static-dir matching this regular expression")
+	flags.IntP("max-urls", "", 0, "Audit an evenly spread sample of at most this many URLs from --url-file, --sitemap, --crawl or --static-dir. Zero means all")
+	flags.StringP("login-script", "", "", "Puppeteer script to run in the same browser before the audit, e.g. to log in")
+	flags.StringP("config-path", "", "", "Lighthouse config file, e.g. with custom audits or settings")
+	flags.StringSliceP("only-categories", "", []string{}, "Only run these categories, comma separated")
+	flags.StringSliceP("only-audits", "", []string{}, "Only run these audits, comma separated")
+	flags.StringSliceP("skip-audits", "", []string{}, "Skip these audits, comma separated")
+	flags.IntP("runs", "", 1, "Number of lighthouse runs per URL. The median run is kept as the report")
+	flags.StringP("median-metric", "", lighthouse.DefaultMedianMetric, "Category or audit ID used to pick the median run")
+	flags.IntP("concurrency", "c", 1, "Number of URLs to audit at the same time")
+	flags.StringP("runtime", "", lighthouse.RuntimeDocker, "How to run lighthouse, either 'docker', 'docker-api' to use the Docker Engine API without the docker client, 'podman' or 'native' for a lighthouse binary in PATH")
+	flags.StringP("image", "", lighthouse.DefaultImage, "Lighthouse container image")
+	flags.StringP("image-tag", "", lighthouse.DefaultImageTag, "Lighthouse container image tag")
+	flags.StringP("image-digest", "", "", "Pin the lighthouse container image by digest (sha256:...), overrides --image-tag")
+	flags.StringP("image-archive", "", "", "Load the lighthouse container image from this tarball instead of pulling it")
+	flags.StringP("pull", "", lighthouse.PullMissing, "Image pull policy, either 'always', 'missing' or 'never'")
+	flags.StringP("container-user", "", lighthouse.UserAuto, "User to run the lighthouse container as: 'auto' for the invoking user, 'image' for the image's user with reports handed over afterwards, or a numeric uid:gid")
+	flags.Float64P("cpus", "", 0, "Number of CPUs the lighthouse container may use, e.g. 1.5. Zero means no limit")
+	flags.StringP("memory", "", "", "Memory limit of the lighthouse container, e.g. '2g'")
+	flags.StringP("cpuset-cpus", "", "", "CPUs to pin the lighthouse container to, e.g. '0-1' or '0,2'")
+	flags.StringP("shm-size", "", "", "Size of /dev/shm in the lighthouse container, e.g. '1g'. Default is a host directory")
+	flags.Float64P("min-benchmark-index", "", 0, "Warn about runs with a lower lighthouse benchmark index, the host is too slow or busy")
+	flags.Float64P("max-benchmark-index", "", 0, "Warn about runs with a higher lighthouse benchmark index")
+	flags.BoolP("benchmark-fail", "", false, "Fail runs outside the benchmark index range instead of warning, so they are retried")
+	flags.StringArrayP("threshold", "", []string{}, "Fail audits scoring below this minimum as 'category=score', e.g. 'performance=90', can be used multiple times")
+	flags.DurationP("timeout", "", 0, "Maximum duration of each lighthouse run, e.g. '2m'. Zero means no timeout")
+	flags.IntP("retries", "", 0, "Number of times to repeat a failed lighthouse run or a run with a broken report")
+	flags.DurationP("retry-backoff", "", lighthouse.DefaultRetryBackoff, "Delay before the first retry, doubled for every further retry")
+	flags.StringP("wait-for", "", "", "Before auditing, wait until this URL or tcp://host:port address answers from within the lighthouse network")
+	flags.DurationP("wait-timeout", "", lighthouse.DefaultWaitTimeout, "Maximum time to wait for --wait-for")
+	flags.IntP("wait-status", "", 0, "HTTP status code expected from --wait-for. Default is any status below 400")
+	flags.StringP("wait-body", "", "", "Substring expected in the HTTP response body of --wait-for")
+	flags.StringP("serve-image", "", "", "Run this image as the site under test in a private network. URLs starting with '/' are audited against it")
+	flags.IntP("serve-port", "", 80, "Port the --serve-image container listens on")
+	flags.StringP("static-dir", "", "", "Serve this directory from an in-process web server for the audit. URLs starting with '/' are audited against it")
+	flags.StringArrayP("path", "", []string{}, "Path of --static-dir to audit, can be used multiple times. Default is all HTML files")
+	flags.StringP("compose-file", "", "", "Bring up this docker-compose stack for the audit. URLs starting with '/' are audited against --compose-service")
+	flags.StringP("compose-service", "", "", "Compose service serving the site under test")
+	flags.IntP("compose-port", "", 80, "Port --compose-service listens on")
 }
 
 func audit(cmd *cobra.Command, args []string) {
-	urls, err := cmd.Flags().GetStringArray("url")
+	flags, err := resolveFlags(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	urls, err := flags.GetStringArray("url")
 	if err != nil {
 		fmt.Println("Error while reading --url flag:")
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	names, err := cmd.Flags().GetStringArray("name")
+	names, err := flags.GetStringArray("name")
 	if err != nil {
 		fmt.Println("Error while reading --name flag:")
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	dockerLinks, err := cmd.Flags().GetStringArray("docker-link")
+	dockerLinks, err := flags.GetStringArray("docker-link")
 	if err != nil {
 		fmt.Println("Error while reading --docker-link flag:")
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	formFactor, err := cmd.Flags().GetString("form-factor")
+	dockerNetwork, err := flags.GetString("docker-network")
+	if err != nil {
+		fmt.Println("Error while reading --docker-network flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	addHosts, err := flags.GetStringArray("add-host")
+	if err != nil {
+		fmt.Println("Error while reading --add-host flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	formFactorList, err := flags.GetString("form-factor")
+	if err != nil {
+		fmt.Println("Error while reading --form-factor flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	formFactors, err := lighthouse.ParseFormFactors(formFactorList)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	emulations, err := emulationsFromFlags(flags, formFactors)
 	if err != nil {
-		fmt.Println("Error while reading --name flag:")
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	ignoreCertErrors, err := cmd.Flags().GetBool("ignore-certificate-errors")
+	ignoreCertErrors, err := flags.GetBool("ignore-certificate-errors")
 	if err != nil {
 		fmt.Println("Error while reading --ignore-certificate-errors flag:")
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	for index, url := range urls {
-		// set automatic output name if none given
-		if len(names) < (index + 1) {
-			t := time.Now()
-			names = append(names, t.Format("20060102-150405")+fmt.Sprintf("-%s-%d", formFactor, index+1))
-		}
+	extraHeaders, err := extraHeadersFromFlags(flags)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	loginScript, err := flags.GetString("login-script")
+	if err != nil {
+		fmt.Println("Error while reading --login-script flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	configPath, err := flags.GetString("config-path")
+	if err != nil {
+		fmt.Println("Error while reading --config-path flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	onlyCategories, err := flags.GetStringSlice("only-categories")
+	if err != nil {
+		fmt.Println("Error while reading --only-categories flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	onlyAudits, err := flags.GetStringSlice("only-audits")
+	if err != nil {
+		fmt.Println("Error while reading --only-audits flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	skipAudits, err := flags.GetStringSlice("skip-audits")
+	if err != nil {
+		fmt.Println("Error while reading --skip-audits flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	runs, err := flags.GetInt("runs")
+	if err != nil {
+		fmt.Println("Error while reading --runs flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	medianMetric, err := flags.GetString("median-metric")
+	if err != nil {
+		fmt.Println("Error while reading --median-metric flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	concurrency, err := flags.GetInt("concurrency")
+	if err != nil {
+		fmt.Println("Error while reading --concurrency flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if concurrency > runtime.NumCPU() {
+		fmt.Printf("Warning: --concurrency %d is higher than the number of CPUs (%d). Scores may be less reproducible.\n", concurrency, runtime.NumCPU())
+	}
+
+	runtimeName, err := flags.GetString("runtime")
+	if err != nil {
+		fmt.Println("Error while reading --runtime flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	image, err := flags.GetString("image")
+	if err != nil {
+		fmt.Println("Error while reading --image flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	imageTag, err := flags.GetString("image-tag")
+	if err != nil {
+		fmt.Println("Error while reading --image-tag flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	imageDigest, err := flags.GetString("image-digest")
+	if err != nil {
+		fmt.Println("Error while reading --image-digest flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	imageArchive, err := flags.GetString("image-archive")
+	if err != nil {
+		fmt.Println("Error while reading --image-archive flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	pull, err := flags.GetString("pull")
+	if err != nil {
+		fmt.Println("Error while reading --pull flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	containerUser, err := flags.GetString("container-user")
+	if err != nil {
+		fmt.Println("Error while reading --container-user flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	timeout, err := flags.GetDuration("timeout")
+	if err != nil {
+		fmt.Println("Error while reading --timeout flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	retries, err := flags.GetInt("retries")
+	if err != nil {
+		fmt.Println("Error while reading --retries flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	resources, err := resourcesFromFlags(flags)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	benchmarkRange, err := benchmarkRangeFromFlags(flags)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	thresholds, err := thresholdsFromFlags(flags)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	retryBackoff, err := flags.GetDuration("retry-backoff")
+	if err != nil {
+		fmt.Println("Error while reading --retry-backoff flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	waitFor, err := flags.GetString("wait-for")
+	if err != nil {
+		fmt.Println("Error while reading --wait-for flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	waitTimeout, err := flags.GetDuration("wait-timeout")
+	if err != nil {
+		fmt.Println("Error while reading --wait-timeout flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	waitStatus, err := flags.GetInt("wait-status")
+	if err != nil {
+		fmt.Println("Error while reading --wait-status flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	waitBody, err := flags.GetString("wait-body")
+	if err != nil {
+		fmt.Println("Error while reading --wait-body flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	serveImage, err := flags.GetString("serve-image")
+	if err != nil {
+		fmt.Println("Error while reading --serve-image flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	servePort, err := flags.GetInt("serve-port")
+	if err != nil {
+		fmt.Println("Error while reading --serve-port flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	composeFile, err := flags.GetString("compose-file")
+	if err != nil {
+		fmt.Println("Error while reading --compose-file flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	composeService, err := flags.GetString("compose-service")
+	if err != nil {
+		fmt.Println("Error while reading --compose-service flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	composePort, err := flags.GetInt("compose-port")
+	if err != nil {
+		fmt.Println("Error while reading --compose-port flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	staticDir, err := flags.GetString("static-dir")
+	if err != nil {
+		fmt.Println("Error while reading --static-dir flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	outputDir, err := flags.GetString("output-dir")
+	if err != nil {
+		fmt.Println("Error while reading --output-dir flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
-		_, err := lighthouse.AuditURL(url, names[index], formFactor, dockerLinks, ignoreCertErrors)
+	nameTemplateText, err := flags.GetString("name-template")
+	if err != nil {
+		fmt.Println("Error while reading --name-template flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	outputFormats, err := flags.GetStringSlice("output-format")
+	if err != nil {
+		fmt.Println("Error while reading --output-format flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	saveAssets, err := flags.GetBool("save-assets")
+	if err != nil {
+		fmt.Println("Error while reading --save-assets flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var nameTemplate *output.NameTemplate
+	if nameTemplateText != "" {
+		nameTemplate, err = output.ParseNameTemplate(nameTemplateText)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
 	}
 
+	// Cancel running audits on SIGINT or SIGTERM, so containers and
+	// temporary files get cleaned up. A second signal exits right away.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	{
+		signals := make(chan os.Signal, 2)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		go handleSignals(signals, cancel, os.Exit)
+	}
+
+	// From here on, exit through cleanups to remove what we started.
+	var cleanups cleanup
+	defer cleanups.run()
+
+	listed, err := listedURLsFromFlags(ctx, flags)
+	if err != nil {
+		fmt.Println(err)
+		cleanups.exit(1)
+	}
+	if len(listed) > 0 {
+		// Names of --url flags are matched by position, so complete them
+		// before adding the listed URLs with their own names. Empty names
+		// are set automatically.
+		if len(names) > len(urls) {
+			names = names[:len(urls)]
+		}
+		for len(names) < len(urls) {
+			names = append(names, "")
+		}
+		for _, e := range listed {
+			urls = append(urls, e.URL)
+			names = append(names, e.Name)
+		}
+	}
+	if len(urls) == 0 {
+		fmt.Println("No URLs to audit")
+		cleanups.exit(1)
+	}
+
+	network := dockerNetwork
+	var servedSite *site
+	if serveImage != "" || composeFile != "" || staticDir != "" {
+		if staticDir != "" {
+			loopbackOnly := lighthouse.LoopbackReachable(lighthouse.RunnerConfig{
+				Runtime:     runtimeName,
+				DockerLinks: dockerLinks,
+				Network:     dockerNetwork,
+			}, runtime.GOOS)
+			servedSite, err = startStaticSite(ctx, cliBinary(runtimeName), staticDir, loopbackOnly)
+		} else if serveImage != "" {
+			var client *docker.Client
+			client, err = docker.New(docker.Config{Binary: cliBinary(runtimeName)})
+			if err != nil {
+				fmt.Println(err)
+				cleanups.exit(1)
+			}
+
+			servedSite, err = startImageSite(ctx, client, serveImage, servePort)
+		} else {
+			servedSite, err = startComposeSite(ctx, cliBinary(runtimeName), composeFile, composeService, dockerNetwork, composePort)
+		}
+		if servedSite != nil {
+			cleanups.add(servedSite.remove)
+		}
+		if err != nil {
+			fmt.Println(err)
+			if servedSite != nil {
+				servedSite.printLogs()
+			}
+			cleanups.exit(1)
+		}
+
+		if servedSite.network != "" {
+			network = servedSite.network
+		}
+
+		for i := range urls {
+			urls[i] = servedSite.resolve(urls[i])
+		}
+
+		if waitFor == "" {
+			waitFor = servedSite.readyTarget()
+		} else {
+			waitFor = servedSite.resolve(waitFor)
+		}
+	}
+
+	runnerConfig := lighthouse.RunnerConfig{
+		Runtime:      runtimeName,
+		DockerLinks:  dockerLinks,
+		Network:      network,
+		ExtraHosts:   addHosts,
+		Image:        image,
+		ImageTag:     imageTag,
+		ImageDigest:  imageDigest,
+		ImageArchive: imageArchive,
+		Pull:         pull,
+		User:         containerUser,
+		Resources:    resources,
+	}
+
+	// URLs as given, before adapting them to the lighthouse network, for
+	// report names
+	givenURLs := append([]string{}, urls...)
+
+	// Make localhost URLs reach the host rather than the container. The
+	// wait target is adapted along with the audited URLs.
+	{
+		targets := append([]string{waitFor}, urls...)
+		var changes []string
+		runnerConfig, targets, changes = lighthouse.ReachLoopback(runnerConfig, targets, runtime.GOOS)
+		waitFor, urls = targets[0], targets[1:]
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	}
+
+	runner, err := lighthouse.NewRunner(runnerConfig)
+	if err != nil {
+		fmt.Println(err)
+		cleanups.exit(1)
+	}
+
+	err = runner.Prepare(ctx, os.Stdout)
+	if err != nil {
+		fmt.Println(err)
+		cleanups.exit(1)
+	}
+
+	if waitFor != "" {
+		waitConfig := lighthouse.WaitConfig{
+			Target:       waitFor,
+			Timeout:      waitTimeout,
+			ExpectStatus: waitStatus,
+			ExpectBody:   waitBody,
+		}
+
+		err = lighthouse.WaitForReady(ctx, runner, waitConfig, os.Stdout)
+		if err != nil {
+			fmt.Println(err)
+			if servedSite != nil {
+				servedSite.printLogs()
+			}
+			cleanups.exit(1)
+		}
+	}
+
+	err = os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		fmt.Println(err)
+		cleanups.exit(1)
+	}
+
+	start := time.Now()
+	gitSHA := ""
+	if nameTemplate != nil {
+		gitSHA = output.GitSHA()
+	}
+	namer := output.NewNamer(outputDir)
+
+	// With several form factors or presets, reports are named
+	// <name>-<form factor>-<preset>, unless the name template tells them
+	// apart already.
+	suffixFormFactor := len(formFactors) > 1
+	if suffixFormFactor && nameTemplate != nil {
+		suffixFormFactor = !nameTemplate.Distinguishes(func(f *output.NameFields, v string) { f.FormFactor = v }, lighthouse.FormFactorDesktop, lighthouse.FormFactorMobile)
+	}
+	suffixPreset := len(emulations) > 1
+	if suffixPreset && nameTemplate != nil {
+		suffixPreset = !nameTemplate.Distinguishes(func(f *output.NameFields, v string) { f.Preset = v }, emulations[0].preset, emulations[1].preset)
+	}
+
+	configs := []lighthouse.Config{}
+	for index, url := range urls {
+		for _, e := range emulations {
+			for _, formFactor := range e.formFactors {
+				name := ""
+				if index < len(names) {
+					name = names[index]
+				}
+
+				// Templated names never overwrite existing reports.
+				// Given names do, as comparing against a report of a
+				// fixed name relies on it.
+				keepExisting := false
+				suffix := suffixFormFactor
+				if nameTemplate != nil {
+					fields := output.NewNameFields(givenURLs[index], start, gitSHA)
+					fields.Name = name
+					fields.FormFactor = formFactor
+					fields.Preset = e.preset
+					fields.Index = index + 1
+					name, err = nameTemplate.Execute(fields)
+					if err != nil {
+						fmt.Println(err)
+						cleanups.exit(1)
+					}
+					keepExisting = true
+				} else if name == "" {
+					// automatic names always name the form factor
+					name = output.AutoName(start, index)
+					suffix = true
+				}
+				if suffix {
+					name = lighthouse.FormFactorName(name, formFactor)
+				}
+				if suffixPreset {
+					name = fmt.Sprintf("%s-%s", name, e.preset)
+				}
+				name = namer.Unique(name, keepExisting)
+
+				config := lighthouse.Config{
+					URL:              url,
+					Name:             name,
+					OutputDir:        outputDir,
+					OutputFormats:    outputFormats,
+					SaveAssets:       saveAssets,
+					FormFactor:       formFactor,
+					Preset:           e.preset,
+					Throttling:       e.throttling,
+					Screen:           e.screen,
+					IgnoreCertErrors: ignoreCertErrors,
+					Runs:             runs,
+					MedianMetric:     medianMetric,
+					Runner:           runner,
+					Timeout:          timeout,
+					Retries:          retries,
+					BenchmarkRange:   benchmarkRange,
+					Thresholds:       thresholds,
+					RetryBackoff:     retryBackoff,
+					ConfigPath:       configPath,
+					OnlyCategories:   onlyCategories,
+					OnlyAudits:       onlyAudits,
+					SkipAudits:       skipAudits,
+					LoginScript:      loginScript,
+					ExtraHeaders:     extraHeaders,
+				}
+
+				configs = append(configs, config)
+			}
+		}
+	}
+
+	results := lighthouse.AuditURLs(ctx, configs, concurrency, os.Stdout)
+
+	manifestPath, err := writeManifest(outputDir, start, gitSHA, results)
+	if err != nil {
+		fmt.Printf("Could not write manifest: %s\n", err)
+	} else {
+		fmt.Printf("Wrote manifest %s\n", manifestPath)
+	}
+
+	if !printResults(os.Stdout, results) {
+		if servedSite != nil {
+			servedSite.printLogs()
+		}
+		cleanups.exit(1)
+	}
+}
+
+// printResults prints a pass/fail table for all audited URLs and returns
+// true if all audits passed.
+func printResults(out io.Writer, results []lighthouse.Result) bool {
+	table := tablewriter.NewWriter(out)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"URL", "Result", "Report"})
+
+	passed := true
+	for _, r := range results {
+		if r.Err != nil {
+			passed = false
+			table.Append([]string{r.Config.URL, color.RedString("FAIL"), r.Err.Error()})
+		} else {
+			table.Append([]string{r.Config.URL, color.GreenString("PASS"), r.Path})
+		}
+	}
+
+	fmt.Fprintln(out)
+	table.Render()
+
+	return passed
 }
 
 func validateFlags(cmd *cobra.Command, args []string) error {
-	if cmd.Flag("url") == nil {
+	flags, err := resolveFlags(cmd)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if flags.Lookup("url") == nil {
 		return microerror.Maskf(invalidFlagsError, "please specify at least one URL to audit using the --url/-u flag")
 	}
 
-	inputs, err := cmd.Flags().GetStringArray("url")
+	inputs, err := flags.GetStringArray("url")
 	if err != nil {
 		return microerror.Maskf(invalidFlagsError, "could not read values for --url/-u flag")
 	}
-	if len(inputs) < 1 {
-		return microerror.Maskf(invalidFlagsError, "please specify at least one URL to audit via the --url/-u flag")
+	urlFile, err := flags.GetString("url-file")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --url-file flag")
+	}
+	sitemaps, err := flags.GetStringArray("sitemap")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read values for --sitemap flag")
+	}
+	crawl, err := flags.GetString("crawl")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --crawl flag")
+	}
+	staticDirGiven := flags.Changed("static-dir")
+	if len(inputs) < 1 && urlFile == "" && len(sitemaps) == 0 && crawl == "" && !staticDirGiven {
+		return microerror.Maskf(invalidFlagsError, "please specify at least one URL to audit via the --url/-u, --url-file, --sitemap, --crawl or --static-dir flag")
+	}
+	if crawl != "" {
+		if !strings.HasPrefix(crawl, "http://") && !strings.HasPrefix(crawl, "https://") {
+			return microerror.Maskf(invalidFlagsError, "%s must be an absolute http(s) URL", flagRef(flags, "crawl"))
+		}
+		depth, err := flags.GetInt("depth")
+		if err != nil {
+			return microerror.Maskf(invalidFlagsError, "could not read value for --depth flag")
+		}
+		maxPages, err := flags.GetInt("max-pages")
+		if err != nil {
+			return microerror.Maskf(invalidFlagsError, "could not read value for --max-pages flag")
+		}
+		if depth < 0 || maxPages < 1 {
+			return microerror.Maskf(invalidFlagsError, "%s must be 0 or greater and %s 1 or greater", flagRef(flags, "depth"), flagRef(flags, "max-pages"))
+		}
+	} else if flags.Changed("depth") || flags.Changed("max-pages") {
+		return microerror.Maskf(invalidFlagsError, "%s and %s require --crawl", flagRef(flags, "depth"), flagRef(flags, "max-pages"))
+	}
+	if urlFile != "" {
+		info, err := os.Stat(urlFile)
+		if err != nil || info.IsDir() {
+			return microerror.Maskf(invalidFlagsError, "%s %q is not a readable file", flagRef(flags, "url-file"), urlFile)
+		}
+	}
+	_, _, _, err = urlSelectionFromFlags(flags)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if urlFile == "" && len(sitemaps) == 0 && crawl == "" && !staticDirGiven {
+		for _, f := range []string{"include", "exclude", "max-urls"} {
+			if flags.Changed(f) {
+				return microerror.Maskf(invalidFlagsError, "%s requires --url-file, --sitemap, --crawl or --static-dir", flagRef(flags, f))
+			}
+		}
+	}
+
+	formFactorList, err := flags.GetString("form-factor")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --form-factor/-f flag")
+	}
+	formFactors, err := lighthouse.ParseFormFactors(formFactorList)
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, "form-factor"), err)
+	}
+
+	emulations, err := emulationsFromFlags(flags, formFactors)
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "%s", err)
+	}
+	for _, e := range emulations {
+		err = e.throttling.Validate()
+		if err != nil {
+			return microerror.Maskf(invalidFlagsError, "%s", err)
+		}
+		err = e.screen.Validate()
+		if err != nil {
+			return microerror.Maskf(invalidFlagsError, "%s", err)
+		}
+	}
+
+	_, err = extraHeadersFromFlags(flags)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	loginScript, err := flags.GetString("login-script")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --login-script flag")
+	}
+	if loginScript != "" {
+		info, err := os.Stat(loginScript)
+		if err != nil || info.IsDir() {
+			return microerror.Maskf(invalidFlagsError, "%s %q is not a readable file", flagRef(flags, "login-script"), loginScript)
+		}
+	}
+
+	configPath, err := flags.GetString("config-path")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --config-path flag")
+	}
+	if configPath != "" {
+		info, err := os.Stat(configPath)
+		if err != nil || info.IsDir() {
+			return microerror.Maskf(invalidFlagsError, "%s %q is not a readable file", flagRef(flags, "config-path"), configPath)
+		}
+	}
+
+	runs, err := flags.GetInt("runs")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --runs flag")
+	}
+	if runs < 1 {
+		return microerror.Maskf(invalidFlagsError, "%s must be 1 or greater", flagRef(flags, "runs"))
+	}
+
+	concurrency, err := flags.GetInt("concurrency")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --concurrency/-c flag")
+	}
+	if concurrency < 1 {
+		return microerror.Maskf(invalidFlagsError, "%s must be 1 or greater", flagRef(flags, "concurrency"))
+	}
+
+	retries, err := flags.GetInt("retries")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --retries flag")
+	}
+	if retries < 0 {
+		return microerror.Maskf(invalidFlagsError, "%s must not be negative", flagRef(flags, "retries"))
+	}
+
+	_, err = resourcesFromFlags(flags)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = benchmarkRangeFromFlags(flags)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = thresholdsFromFlags(flags)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	waitFor, err := flags.GetString("wait-for")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --wait-for flag")
+	}
+	if waitFor == "" {
+		for _, name := range []string{"wait-timeout", "wait-status", "wait-body"} {
+			if flags.Changed(name) {
+				return microerror.Maskf(invalidFlagsError, "%s requires --wait-for", flagRef(flags, name))
+			}
+		}
+	}
+
+	runtimeName, err := flags.GetString("runtime")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --runtime flag")
+	}
+	switch runtimeName {
+	case lighthouse.RuntimeDocker, lighthouse.RuntimeDockerAPI, lighthouse.RuntimePodman, lighthouse.RuntimeNative:
+	default:
+		return microerror.Maskf(invalidFlagsError, "%s must be one of 'docker', 'docker-api', 'podman' or 'native'", flagRef(flags, "runtime"))
+	}
+
+	serveImage, err := flags.GetString("serve-image")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --serve-image flag")
+	}
+	if serveImage != "" && runtimeName == lighthouse.RuntimeNative {
+		return microerror.Maskf(invalidFlagsError, "%s can not be used with the native runtime", flagRef(flags, "serve-image"))
+	}
+	if serveImage != "" && flags.Changed("docker-network") {
+		return microerror.Maskf(invalidFlagsError, "%s creates its own network and can not be combined with %s", flagRef(flags, "serve-image"), flagRef(flags, "docker-network"))
+	}
+	if serveImage != "" && flags.Changed("docker-link") {
+		return microerror.Maskf(invalidFlagsError, "%s creates its own network and can not be combined with %s, the site is reachable by the host name in its URLs", flagRef(flags, "serve-image"), flagRef(flags, "docker-link"))
+	}
+	if serveImage == "" && flags.Changed("serve-port") {
+		return microerror.Maskf(invalidFlagsError, "%s requires --serve-image", flagRef(flags, "serve-port"))
+	}
+
+	composeFile, err := flags.GetString("compose-file")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --compose-file flag")
+	}
+	composeService, err := flags.GetString("compose-service")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --compose-service flag")
+	}
+	if composeFile != "" {
+		if composeService == "" {
+			return microerror.Maskf(invalidFlagsError, "%s requires --compose-service", flagRef(flags, "compose-file"))
+		}
+		if serveImage != "" {
+			return microerror.Maskf(invalidFlagsError, "%s can not be combined with %s", flagRef(flags, "compose-file"), flagRef(flags, "serve-image"))
+		}
+		if runtimeName == lighthouse.RuntimeNative {
+			return microerror.Maskf(invalidFlagsError, "%s can not be used with the native runtime", flagRef(flags, "compose-file"))
+		}
+		if flags.Changed("docker-link") {
+			return microerror.Maskf(invalidFlagsError, "%s uses the project's network and can not be combined with %s, services are reachable by name", flagRef(flags, "compose-file"), flagRef(flags, "docker-link"))
+		}
+	} else if composeService != "" || flags.Changed("compose-port") {
+		return microerror.Maskf(invalidFlagsError, "%s and %s require --compose-file", flagRef(flags, "compose-service"), flagRef(flags, "compose-port"))
+	}
+
+	outputDir, err := flags.GetString("output-dir")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --output-dir flag")
+	}
+	if info, err := os.Stat(outputDir); err == nil && !info.IsDir() {
+		return microerror.Maskf(invalidFlagsError, "%s %q is not a directory", flagRef(flags, "output-dir"), outputDir)
+	}
+	nameTemplate, err := flags.GetString("name-template")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --name-template flag")
+	}
+	if nameTemplate != "" {
+		_, err = output.ParseNameTemplate(nameTemplate)
+		if err != nil {
+			return microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, "name-template"), err)
+		}
+	}
+
+	outputFormats, err := flags.GetStringSlice("output-format")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --output-format flag")
+	}
+	err = lighthouse.ValidateFormats(outputFormats)
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, "output-format"), err)
+	}
+
+	staticDir, err := flags.GetString("static-dir")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --static-dir flag")
+	}
+	paths, err := flags.GetStringArray("path")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read values for --path flag")
+	}
+	if staticDir != "" {
+		info, err := os.Stat(staticDir)
+		if err != nil || !info.IsDir() {
+			return microerror.Maskf(invalidFlagsError, "%s %q is not a directory", flagRef(flags, "static-dir"), staticDir)
+		}
+		if serveImage != "" || composeFile != "" {
+			return microerror.Maskf(invalidFlagsError, "%s can not be combined with %s or %s", flagRef(flags, "static-dir"), flagRef(flags, "serve-image"), flagRef(flags, "compose-file"))
+		}
+		for _, p := range paths {
+			if !strings.HasPrefix(p, "/") {
+				return microerror.Maskf(invalidFlagsError, "%s %q must start with '/'", flagRef(flags, "path"), p)
+			}
+		}
+	} else if len(paths) > 0 {
+		return microerror.Maskf(invalidFlagsError, "%s requires --static-dir", flagRef(flags, "path"))
+	}
+
+	if serveImage == "" && composeFile == "" && staticDir == "" {
+		// sitemaps and crawled pages always have absolute URLs, but URL
+		// files may list paths
+		pathInputs := inputs
+		if urlFile != "" {
+			entries, err := urllist.ReadFile(urlFile)
+			if err != nil {
+				return microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, "url-file"), err)
+			}
+			for _, e := range entries {
+				pathInputs = append(pathInputs, e.URL)
+			}
+		}
+		for _, u := range pathInputs {
+			if strings.HasPrefix(u, "/") {
+				return microerror.Maskf(invalidFlagsError, "URL %q is a path, which requires --serve-image, --compose-file or --static-dir", u)
+			}
+		}
+	}
+
+	dockerNetwork, err := flags.GetString("docker-network")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --docker-network flag")
+	}
+	if dockerNetwork != "" && flags.Changed("docker-link") {
+		return microerror.Maskf(invalidFlagsError, "%s can not be combined with %s, containers in a user-defined network are reachable by name", flagRef(flags, "docker-link"), flagRef(flags, "docker-network"))
+	}
+
+	if runtimeName == lighthouse.RuntimeNative {
+		for _, name := range []string{"image", "image-tag", "image-digest", "image-archive", "pull", "container-user", "cpus", "memory", "cpuset-cpus", "shm-size", "docker-link", "docker-network", "add-host"} {
+			if flags.Changed(name) {
+				return microerror.Maskf(invalidFlagsError, "%s can not be used with the native runtime", flagRef(flags, name))
+			}
+		}
+	}
+
+	pull, err := flags.GetString("pull")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --pull flag")
+	}
+	switch pull {
+	case lighthouse.PullAlways, lighthouse.PullMissing, lighthouse.PullNever:
+	default:
+		return microerror.Maskf(invalidFlagsError, "%s must be one of 'always', 'missing' or 'never'", flagRef(flags, "pull"))
 	}
 
 	return nil
@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+)
+
+// TestValidateFlagsNetworks checks that network settings conflicting with
+// a served site are rejected before the site is started.
+func TestValidateFlagsNetworks(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{
+			name: "serve image",
+			args: []string{"--serve-image", "myapp:pr-123", "--url", "/"},
+		},
+		{
+			name:          "serve image with network",
+			args:          []string{"--serve-image", "myapp:pr-123", "--docker-network", "ci", "--url", "/"},
+			expectedError: "--serve-image creates its own network and can not be combined with --docker-network",
+		},
+		{
+			name:          "serve image with link",
+			args:          []string{"--serve-image", "myapp:pr-123", "--docker-link", "db:db", "--url", "/"},
+			expectedError: "--serve-image creates its own network and can not be combined with --docker-link",
+		},
+		{
+			name: "compose file with network",
+			args: []string{"--compose-file", "docker-compose.yml", "--compose-service", "web", "--docker-network", "frontend", "--url", "/"},
+		},
+		{
+			name:          "compose file with link",
+			args:          []string{"--compose-file", "docker-compose.yml", "--compose-service", "web", "--docker-link", "db:db", "--url", "/"},
+			expectedError: "--compose-file uses the project's network and can not be combined with --docker-link",
+		},
+		{
+			name:          "network with link",
+			args:          []string{"--docker-network", "ci", "--docker-link", "db:db", "--url", "http://web/"},
+			expectedError: "--docker-link can not be combined with --docker-network",
+		},
+	}
+
+	for _, tc := range testCases {
+		cmd := &cobra.Command{Use: "audit"}
+		defineFlags(cmd.Flags())
+		err := cmd.Flags().Parse(tc.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = validateFlags(cmd, nil)
+		if tc.expectedError == "" && err != nil {
+			t.Errorf("%s: unexpected error %s", tc.name, err)
+		} else if tc.expectedError != "" && (!IsInvalidFlagsError(err) || !strings.Contains(err.Error(), tc.expectedError)) {
+			t.Errorf("%s: expected %q, got %v", tc.name, tc.expectedError, err)
+		}
+	}
+}
+
+// TestValidateFlagsConcurrency checks that at least one audit has to run at
+// a time, wherever --concurrency is set.
+func TestValidateFlagsConcurrency(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          []string
+		env           string
+		expectedError string
+	}{
+		{
+			name: "default",
+			args: []string{"--url", "https://example.com/"},
+		},
+		{
+			name: "shorthand",
+			args: []string{"-c", "4", "--url", "https://example.com/"},
+		},
+		{
+			name:          "zero",
+			args:          []string{"--concurrency", "0", "--url", "https://example.com/"},
+			expectedError: "--concurrency must be 1 or greater",
+		},
+		{
+			name:          "negative from environment",
+			args:          []string{"--url", "https://example.com/"},
+			env:           "-1",
+			expectedError: "LHK_CONCURRENCY must be 1 or greater",
+		},
+	}
+
+	for _, tc := range testCases {
+		if tc.env != "" {
+			os.Setenv("LHK_CONCURRENCY", tc.env)
+		}
+
+		cmd := &cobra.Command{Use: "audit"}
+		defineFlags(cmd.Flags())
+		err := cmd.Flags().Parse(tc.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = validateFlags(cmd, nil)
+		os.Unsetenv("LHK_CONCURRENCY")
+		if tc.expectedError == "" && err != nil {
+			t.Errorf("%s: unexpected error %s", tc.name, err)
+		} else if tc.expectedError != "" && (!IsInvalidFlagsError(err) || !strings.Contains(err.Error(), tc.expectedError)) {
+			t.Errorf("%s: expected %q, got %v", tc.name, tc.expectedError, err)
+		}
+	}
+}
+
+// TestPrintResults checks the result table and that any failed audit fails
+// the command.
+func TestPrintResults(t *testing.T) {
+	pass := lighthouse.Result{Config: lighthouse.Config{URL: "https://example.com/one"}, Path: "one.json"}
+	fail := lighthouse.Result{Config: lighthouse.Config{URL: "https://example.com/two"}, Err: microerror.Mask(invalidFlagsError)}
+
+	testCases := []struct {
+		name           string
+		results        []lighthouse.Result
+		expectedPassed bool
+		expectedRows   []string
+	}{
+		{
+			name:           "all passed",
+			results:        []lighthouse.Result{pass},
+			expectedPassed: true,
+			expectedRows:   []string{"https://example.com/one | PASS   | one.json"},
+		},
+		{
+			name:           "one failed",
+			results:        []lighthouse.Result{pass, fail},
+			expectedPassed: false,
+			expectedRows: []string{
+				"https://example.com/one | PASS   | one.json",
+				"https://example.com/two | FAIL   | invalid flags error",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+		passed := printResults(&out, tc.results)
+		if passed != tc.expectedPassed {
+			t.Errorf("%s: expected passed %t, got %t", tc.name, tc.expectedPassed, passed)
+		}
+		for _, row := range tc.expectedRows {
+			if !strings.Contains(out.String(), row) {
+				t.Errorf("%s: expected row %q in:\n%s", tc.name, row, out.String())
+			}
+		}
+	}
+}
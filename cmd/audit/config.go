@@ -0,0 +1,239 @@
+package audit
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/giantswarm/lighthouse-keeper/service/config"
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+)
+
+// envPrefix starts the names of the environment variables setting flags,
+// e.g. LHK_RUNS for --runs.
+const envPrefix = "LHK_"
+
+// basicAuthEnv is the default environment variable holding basic auth
+// credentials.
+const basicAuthEnv = envPrefix + "BASIC_AUTH"
+
+// envName returns the environment variable setting the flag.
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flag, "-", "_", -1))
+}
+
+// originAnnotation records the environment variable or config file a flag
+// value not given on the command line was taken from.
+const originAnnotation = "origin"
+
+// resolveFlags returns the flags given on the command line, completed with
+// their environment variables and then with the --config file. The values
+// are resolved into a copy, the command's own flags keep telling what was
+// typed.
+func resolveFlags(cmd *cobra.Command) (*pflag.FlagSet, error) {
+	flags := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	defineFlags(flags)
+
+	var err error
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if err == nil && flags.Lookup(f.Name) != nil {
+			err = copyFlag(flags, cmd.Flags(), f)
+		}
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = applyEnvironment(flags)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = applyConfigFile(flags)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return flags, nil
+}
+
+// copyFlag sets the flag in dst to its value in src.
+func copyFlag(dst, src *pflag.FlagSet, f *pflag.Flag) error {
+	var values []string
+	var err error
+	switch f.Value.Type() {
+	case "stringArray":
+		values, err = src.GetStringArray(f.Name)
+	case "stringSlice":
+		values, err = src.GetStringSlice(f.Name)
+		if len(values) == 0 {
+			values = []string{""}
+		}
+	default:
+		values = []string{f.Value.String()}
+	}
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --%s flag", f.Name)
+	}
+
+	for _, v := range values {
+		err = dst.Set(f.Name, v)
+		if err != nil {
+			return microerror.Maskf(invalidFlagsError, "--%s: %s", f.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// flagRef names where the value of a flag came from in error messages: its
+// environment variable, the key of the config file, or the flag itself.
+func flagRef(flags *pflag.FlagSet, name string) string {
+	f := flags.Lookup(name)
+	if f != nil && len(f.Annotations[originAnnotation]) > 0 {
+		return f.Annotations[originAnnotation][0]
+	}
+
+	return "--" + name
+}
+
+// applyEnvironment sets the flags not given on the command line from their
+// environment variables. Lists take a single value, or comma separated
+// values where the flag accepts them.
+func applyEnvironment(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if err != nil || f.Changed || !ok {
+			return
+		}
+
+		setErr := flags.Set(f.Name, value)
+		if setErr != nil {
+			err = microerror.Maskf(invalidFlagsError, "%s: %s", envName(f.Name), setErr)
+			return
+		}
+		err = flags.SetAnnotation(f.Name, originAnnotation, []string{envName(f.Name)})
+	})
+
+	return err
+}
+
+// applyConfigFile sets the flags that are neither given on the command line
+// nor by environment variables from the --config file. The sites of the
+// file are only audited if no other source of URLs is given.
+func applyConfigFile(flags *pflag.FlagSet) error {
+	path, err := flags.GetString("config")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --config flag")
+	}
+	if path == "" {
+		return nil
+	}
+
+	c, err := config.Read(path)
+	if config.IsInvalidConfigError(err) {
+		return microerror.Maskf(invalidFlagsError, "%s", err)
+	} else if err != nil {
+		return microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, "config"), err)
+	}
+
+	// set sets a flag from the config file, remembering the key it came
+	// from
+	set := func(flag, key, value string) error {
+		err := flags.Set(flag, value)
+		if err != nil {
+			return microerror.Maskf(invalidFlagsError, "%s in %s: %s", key, path, err)
+		}
+		return flags.SetAnnotation(flag, originAnnotation, []string{key + " in " + path})
+	}
+
+	urlsGiven := false
+	for _, name := range []string{"url", "url-file", "sitemap", "crawl", "static-dir"} {
+		urlsGiven = urlsGiven || flags.Changed(name)
+	}
+	if !urlsGiven {
+		namesGiven := flags.Changed("name")
+		for _, e := range c.Entries() {
+			err = set("url", "sites", e.URL)
+			if err == nil && !namesGiven {
+				err = set("name", "sites", e.Name)
+			}
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+	}
+
+	thresholds := []string{}
+	for category, score := range c.Thresholds {
+		thresholds = append(thresholds, category+"="+strconv.FormatFloat(score, 'f', -1, 64))
+	}
+	sort.Strings(thresholds)
+
+	runs := ""
+	if c.Runs > 0 {
+		runs = strconv.Itoa(c.Runs)
+	}
+
+	values := []struct {
+		flag   string
+		key    string
+		values []string
+	}{
+		{flag: "form-factor", key: "formFactors", values: []string{strings.Join(c.FormFactors, ",")}},
+		{flag: "preset", key: "presets", values: []string{strings.Join(c.Presets, ",")}},
+		{flag: "runs", key: "runs", values: []string{runs}},
+		{flag: "header", key: "auth.headers", values: c.Auth.Headers},
+		{flag: "cookie-file", key: "auth.cookieFile", values: []string{c.Auth.CookieFile}},
+		{flag: "basic-auth-env", key: "auth.basicAuthEnv", values: []string{c.Auth.BasicAuthEnv}},
+		{flag: "login-script", key: "auth.loginScript", values: []string{c.Auth.LoginScript}},
+		{flag: "threshold", key: "thresholds", values: thresholds},
+	}
+	for _, v := range values {
+		if flags.Changed(v.flag) {
+			continue
+		}
+		for _, value := range v.values {
+			if value == "" {
+				continue
+			}
+			err = set(v.flag, v.key, value)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// thresholdsFromFlags returns the minimum category scores given with
+// --threshold.
+func thresholdsFromFlags(flags *pflag.FlagSet) (lighthouse.Thresholds, error) {
+	values, err := flags.GetStringArray("threshold")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read value for --threshold flag")
+	}
+
+	thresholds := lighthouse.Thresholds{}
+	for _, v := range values {
+		category, score, err := lighthouse.ParseThreshold(v)
+		if err != nil {
+			return nil, microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, "threshold"), err)
+		}
+		thresholds[category] = score
+	}
+
+	err = thresholds.Validate()
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, "threshold"), err)
+	}
+
+	return thresholds, nil
+}
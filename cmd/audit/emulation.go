@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/pflag"
+
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+)
+
+// emulation is an emulation preset with the throttling and screen flags
+// applied on top, and the form factors to audit with it.
+type emulation struct {
+	preset      string
+	throttling  lighthouse.Throttling
+	screen      lighthouse.Screen
+	formFactors []string
+}
+
+// emulationsFromFlags returns an emulation for each preset given with
+// --preset, or a single one without a preset.
+func emulationsFromFlags(flags *pflag.FlagSet, formFactors []string) ([]emulation, error) {
+	value, err := flags.GetString("preset")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read value for --preset flag")
+	}
+
+	presetNames := []string{}
+	seen := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			presetNames = append(presetNames, name)
+			seen[name] = true
+		}
+	}
+	if len(presetNames) == 0 {
+		presetNames = []string{""}
+	}
+
+	emulations := []emulation{}
+	for _, name := range presetNames {
+		throttling, screen, presetFormFactors, err := emulationFromFlags(flags, name, formFactors)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		emulations = append(emulations, emulation{
+			preset:      name,
+			throttling:  throttling,
+			screen:      screen,
+			formFactors: presetFormFactors,
+		})
+	}
+
+	return emulations, nil
+}
+
+// emulationFromFlags returns the throttling, screen and form factors to use,
+// starting from the preset and applying the explicitly given flags on top.
+// A preset for a specific form factor is only audited with that one.
+func emulationFromFlags(flags *pflag.FlagSet, presetName string, formFactors []string) (lighthouse.Throttling, lighthouse.Screen, []string, error) {
+	var preset lighthouse.Preset
+	if presetName != "" {
+		var err error
+		preset, err = lighthouse.GetPreset(presetName)
+		if err != nil {
+			return lighthouse.Throttling{}, lighthouse.Screen{}, nil, microerror.Mask(err)
+		}
+
+		if preset.FormFactor != "" {
+			if flags.Changed("form-factor") && !containsString(formFactors, preset.FormFactor) {
+				return lighthouse.Throttling{}, lighthouse.Screen{}, nil, microerror.Maskf(invalidFlagsError, "preset %q is for form factor %q, not %q", presetName, preset.FormFactor, strings.Join(formFactors, ","))
+			}
+			formFactors = []string{preset.FormFactor}
+		}
+	}
+
+	throttling := preset.Throttling
+	screen := preset.Screen
+
+	var err error
+	if flags.Changed("throttling-method") {
+		throttling.Method, err = flags.GetString("throttling-method")
+	}
+	if err == nil && flags.Changed("throttling-rtt-ms") {
+		throttling.RTTMs, err = flags.GetFloat64("throttling-rtt-ms")
+	}
+	if err == nil && flags.Changed("throttling-throughput-kbps") {
+		throttling.ThroughputKbps, err = flags.GetFloat64("throttling-throughput-kbps")
+	}
+	if err == nil && flags.Changed("throttling-cpu-slowdown") {
+		throttling.CPUSlowdown, err = flags.GetFloat64("throttling-cpu-slowdown")
+	}
+	if err == nil && flags.Changed("screen-width") {
+		screen.Width, err = flags.GetInt("screen-width")
+	}
+	if err == nil && flags.Changed("screen-height") {
+		screen.Height, err = flags.GetInt("screen-height")
+	}
+	if err == nil && flags.Changed("screen-dpr") {
+		screen.DeviceScaleFactor, err = flags.GetFloat64("screen-dpr")
+	}
+	if err != nil {
+		return lighthouse.Throttling{}, lighthouse.Screen{}, nil, microerror.Mask(err)
+	}
+
+	return throttling, screen, formFactors, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+	"github.com/giantswarm/lighthouse-keeper/service/output"
+)
+
+// writeManifest lists the results of the run in the manifest of the output
+// directory.
+func writeManifest(outputDir string, start time.Time, gitSHA string, results []lighthouse.Result) (string, error) {
+	manifest := output.Manifest{
+		Started: start,
+		GitSHA:  gitSHA,
+		Reports: []output.ManifestEntry{},
+	}
+
+	for _, r := range results {
+		entry := output.ManifestEntry{
+			URL:        r.Config.URL,
+			Name:       r.Config.Name,
+			FormFactor: r.Config.FormFactor,
+			Preset:     r.Config.Preset,
+		}
+
+		if r.Err != nil {
+			entry.Error = r.Err.Error()
+		}
+		// reports failing a threshold are listed along with the error
+		if r.Path != "" {
+			report, err := filepath.Rel(outputDir, r.Path)
+			if err != nil {
+				return "", microerror.Mask(err)
+			}
+			entry.Report = filepath.ToSlash(report)
+			entry.Metadata = filepath.ToSlash(lighthouse.MetadataPath(report))
+			base := strings.TrimSuffix(entry.Report, ".json")
+			for _, f := range r.Config.OutputFormats {
+				switch f {
+				case lighthouse.FormatHTML:
+					entry.HTML = base + ".html"
+				case lighthouse.FormatCSV:
+					entry.CSV = base + ".csv"
+				}
+			}
+			if r.Config.Runs > 1 {
+				entry.Summary = filepath.ToSlash(fmt.Sprintf("%s.summary.json", r.Config.Name))
+			}
+		}
+
+		manifest.Reports = append(manifest.Reports, entry)
+	}
+
+	path, err := output.WriteManifest(outputDir, manifest)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/pflag"
+
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+)
+
+// resourcesFromFlags collects the container resource limits given with
+// --cpus, --memory, --cpuset-cpus and --shm-size.
+func resourcesFromFlags(flags *pflag.FlagSet) (lighthouse.Resources, error) {
+	var resources lighthouse.Resources
+
+	cpus, err := flags.GetFloat64("cpus")
+	if err != nil {
+		return resources, microerror.Maskf(invalidFlagsError, "could not read value for --cpus flag")
+	}
+	resources.CPUs = cpus
+
+	resources.CPUSet, err = flags.GetString("cpuset-cpus")
+	if err != nil {
+		return resources, microerror.Maskf(invalidFlagsError, "could not read value for --cpuset-cpus flag")
+	}
+
+	for _, f := range []struct {
+		name string
+		size *int64
+	}{
+		{"memory", &resources.Memory},
+		{"shm-size", &resources.ShmSize},
+	} {
+		value, err := flags.GetString(f.name)
+		if err != nil {
+			return resources, microerror.Maskf(invalidFlagsError, "could not read value for --%s flag", f.name)
+		}
+		if value == "" {
+			continue
+		}
+		*f.size, err = lighthouse.ParseSize(value)
+		if err != nil {
+			return resources, microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, f.name), err)
+		}
+	}
+
+	err = resources.Validate()
+	if err != nil {
+		return resources, microerror.Maskf(invalidFlagsError, "%s", err)
+	}
+
+	return resources, nil
+}
+
+// benchmarkRangeFromFlags returns the accepted range of the benchmark index
+// given with --min-benchmark-index, --max-benchmark-index and
+// --benchmark-fail.
+func benchmarkRangeFromFlags(flags *pflag.FlagSet) (lighthouse.BenchmarkRange, error) {
+	var r lighthouse.BenchmarkRange
+	var err error
+
+	r.Min, err = flags.GetFloat64("min-benchmark-index")
+	if err != nil {
+		return r, microerror.Maskf(invalidFlagsError, "could not read value for --min-benchmark-index flag")
+	}
+	r.Max, err = flags.GetFloat64("max-benchmark-index")
+	if err != nil {
+		return r, microerror.Maskf(invalidFlagsError, "could not read value for --max-benchmark-index flag")
+	}
+	r.Fail, err = flags.GetBool("benchmark-fail")
+	if err != nil {
+		return r, microerror.Maskf(invalidFlagsError, "could not read value for --benchmark-fail flag")
+	}
+
+	err = r.Validate()
+	if err != nil {
+		return r, microerror.Maskf(invalidFlagsError, "%s", err)
+	}
+	if r.Fail && r.Min == 0 && r.Max == 0 {
+		return r, microerror.Maskf(invalidFlagsError, "%s requires --min-benchmark-index or --max-benchmark-index", flagRef(flags, "benchmark-fail"))
+	}
+
+	return r, nil
+}
@@ -0,0 +1,231 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/lighthouse-keeper/service/compose"
+	"github.com/giantswarm/lighthouse-keeper/service/docker"
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+	"github.com/giantswarm/lighthouse-keeper/service/static"
+)
+
+// siteAlias is the host name of the --serve-image container in its network.
+const siteAlias = "site"
+
+// site is the site under test, started by the audit command in a network
+// the lighthouse container joins.
+type site struct {
+	// host and port the site is reachable at from within network.
+	host    string
+	port    int
+	network string
+
+	// logs returns the output of the site.
+	logs func() (string, error)
+	// remove stops the site and removes everything created for it.
+	remove func()
+}
+
+// cliBinary returns the command line client starting sites for the runtime.
+// The Docker Engine API runtime only runs lighthouse itself.
+func cliBinary(runtimeName string) string {
+	if runtimeName == lighthouse.RuntimeDockerAPI {
+		return lighthouse.RuntimeDocker
+	}
+
+	return runtimeName
+}
+
+// siteClient manages the containers and networks of sites started from an
+// image, like docker.Client does.
+type siteClient interface {
+	CreateNetwork(ctx context.Context, name string) error
+	RemoveNetwork(ctx context.Context, name string) error
+	RunDetached(ctx context.Context, config docker.ContainerConfig) error
+	Logs(ctx context.Context, name string) (string, error)
+	RemoveContainer(ctx context.Context, name string) error
+}
+
+// startImageSite creates a private network and starts the given image in it.
+// The returned site must be removed, even if starting it failed.
+func startImageSite(ctx context.Context, client siteClient, image string, port int) (*site, error) {
+	network, err := docker.RandomName()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	container := network + "-site"
+
+	s := &site{
+		host:    siteAlias,
+		port:    port,
+		network: network,
+		logs: func() (string, error) {
+			return client.Logs(context.Background(), container)
+		},
+	}
+
+	networkCreated := false
+	s.remove = func() {
+		// cleanup has to happen even if the audit was cancelled
+		ctx := context.Background()
+
+		err := client.RemoveContainer(ctx, container)
+		if err != nil {
+			fmt.Printf("Could not remove site container: %s\n", err)
+		}
+
+		if networkCreated {
+			err = client.RemoveNetwork(ctx, network)
+			if err != nil {
+				fmt.Printf("Could not remove network: %s\n", err)
+			}
+		}
+	}
+
+	fmt.Printf("Starting %s in network %s\n", image, network)
+
+	err = client.CreateNetwork(ctx, network)
+	if err != nil {
+		return s, microerror.Mask(err)
+	}
+	networkCreated = true
+
+	err = client.RunDetached(ctx, docker.ContainerConfig{
+		Name:    container,
+		Image:   image,
+		Network: network,
+		Aliases: []string{siteAlias},
+	})
+	if err != nil {
+		return s, microerror.Mask(err)
+	}
+
+	return s, nil
+}
+
+// startComposeSite brings up a compose stack in its own project. network
+// names the project network to audit the service in, if the project has
+// several. The returned site must be removed, even if starting it failed.
+func startComposeSite(ctx context.Context, binary, file, service, network string, port int) (*site, error) {
+	project, err := docker.RandomName()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	stack, err := compose.New(compose.Config{
+		Binary:  binary,
+		File:    file,
+		Project: project,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	s := &site{
+		host: service,
+		port: port,
+		logs: func() (string, error) {
+			return stack.Logs(context.Background(), service)
+		},
+		remove: func() {
+			err := stack.Down(context.Background())
+			if err != nil {
+				fmt.Printf("Could not bring down compose project %s: %s\n", project, err)
+			}
+		},
+	}
+
+	fmt.Printf("Starting compose project %s from %s\n", project, file)
+
+	err = stack.Up(ctx)
+	if err != nil {
+		return s, microerror.Mask(err)
+	}
+
+	s.network, err = stack.Network(ctx, network)
+	if compose.IsNetworkError(err) {
+		return s, microerror.Maskf(err, "choose the network of service %s with --docker-network", service)
+	} else if err != nil {
+		return s, microerror.Mask(err)
+	}
+
+	return s, nil
+}
+
+// startStaticSite serves a directory from within this process. The server
+// listens on the loopback interface, which Docker Desktop forwards
+// host.docker.internal to. If lighthouse can't reach it there on Linux, it
+// listens on the gateway of the default bridge network as well, which
+// host.docker.internal resolves to. The returned site must be removed.
+func startStaticSite(ctx context.Context, binary, dir string, loopbackOnly bool) (*site, error) {
+	config := static.Config{Dir: dir, Address: "127.0.0.1:0"}
+	if !loopbackOnly && runtime.GOOS == "linux" {
+		client, err := docker.New(docker.Config{Binary: binary})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		gateway, err := client.BridgeGateway(ctx)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		config.ExtraIPs = []string{gateway}
+	}
+
+	server, err := static.Start(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	s := &site{
+		host: "localhost",
+		port: server.Port(),
+		logs: func() (string, error) {
+			return server.Log(), nil
+		},
+		remove: func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := server.Stop(ctx)
+			if err != nil {
+				fmt.Printf("Could not stop serving %s: %s\n", dir, err)
+			}
+		},
+	}
+
+	fmt.Printf("Serving %s on port %d\n", dir, s.port)
+
+	return s, nil
+}
+
+// resolve turns a path into a URL of the site. Absolute URLs are returned
+// as they are.
+func (s *site) resolve(url string) string {
+	if !strings.HasPrefix(url, "/") {
+		return url
+	}
+
+	return fmt.Sprintf("http://%s:%d%s", s.host, s.port, url)
+}
+
+// readyTarget is the default --wait-for target for the site.
+func (s *site) readyTarget() string {
+	return fmt.Sprintf("tcp://%s:%d", s.host, s.port)
+}
+
+// printLogs prints the output of the site.
+func (s *site) printLogs() {
+	logs, err := s.logs()
+	if err != nil {
+		fmt.Printf("Could not get logs of %s: %s\n", s.host, err)
+		return
+	}
+
+	fmt.Printf("Logs of %s:\n%s\n", s.host, logs)
+}
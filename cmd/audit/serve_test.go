@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/lighthouse-keeper/service/docker"
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+)
+
+// fakeClient records the commands for sites and fails the one named in
+// failOn.
+type fakeClient struct {
+	failOn string
+	calls  []string
+}
+
+func (c *fakeClient) call(format string, args ...interface{}) error {
+	call := fmt.Sprintf(format, args...)
+	c.calls = append(c.calls, call)
+	if c.failOn != "" && strings.HasPrefix(call, c.failOn) {
+		return microerror.Newf("%s failed", c.failOn)
+	}
+
+	return nil
+}
+
+func (c *fakeClient) CreateNetwork(ctx context.Context, name string) error {
+	return c.call("network create %s", name)
+}
+
+func (c *fakeClient) RemoveNetwork(ctx context.Context, name string) error {
+	return c.call("network rm %s", name)
+}
+
+func (c *fakeClient) RunDetached(ctx context.Context, config docker.ContainerConfig) error {
+	return c.call("run %s %s network=%s aliases=%s", config.Name, config.Image, config.Network, strings.Join(config.Aliases, ","))
+}
+
+func (c *fakeClient) Logs(ctx context.Context, name string) (string, error) {
+	return "listening on :8000", c.call("logs %s", name)
+}
+
+func (c *fakeClient) RemoveContainer(ctx context.Context, name string) error {
+	return c.call("rm %s", name)
+}
+
+// probeRunner is a lighthouse.Runner recording the probes of the site.
+type probeRunner struct {
+	probes [][]string
+}
+
+func (r *probeRunner) Prepare(ctx context.Context, out io.Writer) error {
+	return nil
+}
+
+func (r *probeRunner) Metadata() lighthouse.Metadata {
+	return lighthouse.Metadata{}
+}
+
+func (r *probeRunner) Run(ctx context.Context, job lighthouse.Job) error {
+	return nil
+}
+
+func (r *probeRunner) Exec(ctx context.Context, args []string) ([]byte, error) {
+	r.probes = append(r.probes, args)
+	return nil, nil
+}
+
+// TestStartImageSite checks starting the site container in its network,
+// reaching it at the serve port and removing everything afterwards, also
+// when starting failed.
+func TestStartImageSite(t *testing.T) {
+	testCases := []struct {
+		name          string
+		failOn        string
+		expectedCalls []string
+	}{
+		{
+			name: "started",
+			expectedCalls: []string{
+				"network create <network>",
+				"run <network>-site myapp:pr-123 network=<network> aliases=site",
+				"rm <network>-site",
+				"network rm <network>",
+			},
+		},
+		{
+			name:   "container failed",
+			failOn: "run",
+			expectedCalls: []string{
+				"network create <network>",
+				"run <network>-site myapp:pr-123 network=<network> aliases=site",
+				"rm <network>-site",
+				"network rm <network>",
+			},
+		},
+		{
+			name:   "network failed",
+			failOn: "network create",
+			expectedCalls: []string{
+				"network create <network>",
+				"rm <network>-site",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		client := &fakeClient{failOn: tc.failOn}
+		s, err := startImageSite(context.Background(), client, "myapp:pr-123", 8000)
+		if s == nil {
+			t.Fatalf("%s: expected a site to remove, got error %v", tc.name, err)
+		}
+		if tc.failOn != "" && err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		} else if tc.failOn == "" && err != nil {
+			t.Errorf("%s: unexpected error %s", tc.name, err)
+		}
+
+		if tc.failOn == "" {
+			if u := s.resolve("/about"); u != "http://site:8000/about" {
+				t.Errorf("%s: expected the site at port 8000, got %q", tc.name, u)
+			}
+
+			runner := &probeRunner{}
+			err = lighthouse.WaitForReady(context.Background(), runner, lighthouse.WaitConfig{Target: s.readyTarget()}, &bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("%s: unexpected error %s", tc.name, err)
+			}
+			probe := runner.probes[0]
+			if !reflect.DeepEqual(probe[len(probe)-2:], []string{"site", "8000"}) {
+				t.Errorf("%s: expected a probe of site:8000, got %q", tc.name, probe)
+			}
+		}
+
+		s.remove()
+
+		if !strings.HasPrefix(s.network, "lighthouse-keeper-") {
+			t.Errorf("%s: unexpected network name %q", tc.name, s.network)
+		}
+		calls := []string{}
+		for _, c := range client.calls {
+			calls = append(calls, strings.Replace(c, s.network, "<network>", -1))
+		}
+		if !reflect.DeepEqual(calls, tc.expectedCalls) {
+			t.Errorf("%s: expected calls %q, got %q", tc.name, tc.expectedCalls, calls)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/pflag"
+
+	"github.com/giantswarm/lighthouse-keeper/service/crawler"
+	"github.com/giantswarm/lighthouse-keeper/service/static"
+	"github.com/giantswarm/lighthouse-keeper/service/urllist"
+)
+
+// listedURLsFromFlags reads the URLs given with --url-file and --sitemap,
+// found with --crawl or given as --path of --static-dir, applies --include,
+// --exclude and --max-urls and names them after their paths where no name
+// is given.
+func listedURLsFromFlags(ctx context.Context, flags *pflag.FlagSet) ([]urllist.Entry, error) {
+	urlFile, err := flags.GetString("url-file")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read value for --url-file flag")
+	}
+	sitemaps, err := flags.GetStringArray("sitemap")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read values for --sitemap flag")
+	}
+	crawl, err := flags.GetString("crawl")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read value for --crawl flag")
+	}
+	depth, err := flags.GetInt("depth")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read value for --depth flag")
+	}
+	maxPages, err := flags.GetInt("max-pages")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read value for --max-pages flag")
+	}
+	staticDir, err := flags.GetString("static-dir")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read value for --static-dir flag")
+	}
+	paths, err := flags.GetStringArray("path")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read values for --path flag")
+	}
+	urls, err := flags.GetStringArray("url")
+	if err != nil {
+		return nil, microerror.Maskf(invalidFlagsError, "could not read values for --url flag")
+	}
+	include, exclude, maxURLs, err := urlSelectionFromFlags(flags)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	entries := []urllist.Entry{}
+	if urlFile != "" {
+		fileEntries, err := urllist.ReadFile(urlFile)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		entries = append(entries, fileEntries...)
+	}
+	for _, s := range sitemaps {
+		sitemapEntries, err := urllist.ReadSitemap(ctx, nil, s)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		fmt.Printf("Found %d URLs in sitemap %s\n", len(sitemapEntries), s)
+		entries = append(entries, sitemapEntries...)
+	}
+
+	if crawl != "" {
+		crawlConfig := crawler.Config{
+			Start:    crawl,
+			Depth:    depth,
+			MaxPages: maxPages,
+			Output:   os.Stdout,
+		}
+		found, err := crawler.Crawl(ctx, crawlConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		fmt.Printf("Found %d pages crawling %s\n", len(found), crawl)
+		for _, f := range found {
+			entries = append(entries, urllist.Entry{URL: f})
+		}
+	}
+
+	if staticDir != "" {
+		// without any URLs given, audit all pages of the directory
+		if len(paths) == 0 && len(urls) == 0 && len(entries) == 0 {
+			paths, err = static.HTMLPaths(staticDir)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			fmt.Printf("Found %d HTML files in %s\n", len(paths), staticDir)
+		}
+		for _, p := range paths {
+			entries = append(entries, urllist.Entry{URL: p})
+		}
+	}
+
+	entries = urllist.Filter(entries, include, exclude)
+	if maxURLs > 0 && len(entries) > maxURLs {
+		fmt.Printf("Auditing a sample of %d of %d URLs\n", maxURLs, len(entries))
+		entries = urllist.Sample(entries, maxURLs)
+	}
+
+	return urllist.Name(entries), nil
+}
+
+// urlSelectionFromFlags returns the values of --include, --exclude and
+// --max-urls.
+func urlSelectionFromFlags(flags *pflag.FlagSet) (*regexp.Regexp, *regexp.Regexp, int, error) {
+	var include, exclude *regexp.Regexp
+
+	value, err := flags.GetString("include")
+	if err != nil {
+		return nil, nil, 0, microerror.Maskf(invalidFlagsError, "could not read value for --include flag")
+	}
+	if value != "" {
+		include, err = regexp.Compile(value)
+		if err != nil {
+			return nil, nil, 0, microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, "include"), err)
+		}
+	}
+
+	value, err = flags.GetString("exclude")
+	if err != nil {
+		return nil, nil, 0, microerror.Maskf(invalidFlagsError, "could not read value for --exclude flag")
+	}
+	if value != "" {
+		exclude, err = regexp.Compile(value)
+		if err != nil {
+			return nil, nil, 0, microerror.Maskf(invalidFlagsError, "%s: %s", flagRef(flags, "exclude"), err)
+		}
+	}
+
+	maxURLs, err := flags.GetInt("max-urls")
+	if err != nil {
+		return nil, nil, 0, microerror.Maskf(invalidFlagsError, "could not read value for --max-urls flag")
+	}
+	if maxURLs < 0 {
+		return nil, nil, 0, microerror.Maskf(invalidFlagsError, "%s must be 0 or greater", flagRef(flags, "max-urls"))
+	}
+
+	return include, exclude, maxURLs, nil
+}
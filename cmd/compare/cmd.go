@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/giantswarm/microerror"
@@ -14,6 +15,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/giantswarm/lighthouse-keeper/service/commenter"
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
 	"github.com/giantswarm/lighthouse-keeper/service/parser"
 )
 
@@ -28,6 +30,10 @@ var Cmd = &cobra.Command{
     --input lighthouse-a.json --inputlabel before \
     --input lighthouse-b.json --inputlabel after
 
+  lighthouse-keeper compare \
+    --input before --inputlabel before \
+    --input after --inputlabel after
+
   lighthouse-keeper compare \
     --input lighthouse-a.json --inputlabel before \
     --input lighthouse-b.json --inputlabel after \
@@ -69,9 +75,125 @@ func compare(cmd *cobra.Command, args []string) {
 		inputLabel = append(inputLabel, "B")
 	}
 
+	pairs, missing, err := lighthouse.PairReports(input[0], input[1])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	for _, f := range missing {
+		color.Yellow("Warning: there is a %s report for only one of the inputs, it is not compared", f)
+	}
+
+	comparisons := []comparison{}
+	for _, pair := range pairs {
+		c := compareReports(pair)
+		comparisons = append(comparisons, c)
+
+		if pair.FormFactor != "" {
+			fmt.Printf("%s:\n\n", strings.Title(pair.FormFactor))
+		}
+
+		if len(c.warnings) > 0 {
+			color.Yellow("Warning: the reports were created with different settings:")
+			for _, w := range c.warnings {
+				color.Yellow("- %s", w)
+			}
+			fmt.Println()
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetAutoWrapText(false)
+		table.SetHeader(labels(inputLabel))
+
+		for _, v := range c.data {
+			table.Append(v)
+		}
+
+		if len(c.data) > 0 {
+			table.Render()
+		} else if len(inputLabel) == 2 && inputLabel[0] != "" && inputLabel[1] != "" {
+			fmt.Printf("The comparison of lighthouse reports between `%s` and `%s` showed no difference.\n", inputLabel[0], inputLabel[1])
+		}
+		if pair.FormFactor != "" {
+			fmt.Println()
+		}
+	}
+
+	// comment to Github
+	var owner string
+	var repo string
+	var token string
+	var issue int
+	{
+		owner, err = cmd.Flags().GetString("github-owner")
+		if err != nil {
+			fmt.Println("Error while reading --github-owner flag:")
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		repo, err = cmd.Flags().GetString("github-repo")
+		if err != nil {
+			fmt.Println("Error while reading --github-repo flag:")
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		token, err = cmd.Flags().GetString("github-token")
+		if err != nil {
+			fmt.Println("Error while reading --github-token flag:")
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		issue, err = cmd.Flags().GetInt("github-issue")
+		if err != nil {
+			fmt.Println("Error while reading --github-issue flag:")
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if owner != "" && repo != "" && token != "" && issue != 0 {
+			body := commentBody(comparisons, labels(inputLabel), missing)
+			if body != "" {
+				err = commenter.AddComment(token, owner, repo, body, issue)
+				if err != nil {
+					fmt.Println(err)
+				}
+			}
+		}
+	}
+
+}
+
+// comparison holds the differing scores of one pair of reports.
+type comparison struct {
+	formFactor string
+
+	// output table data
+	data [][]string
+
+	// table data that works in markdown, without ANSII escape sequences
+	markdownData [][]string
+
+	// warnings describe settings the reports were created with
+	// differently, as far as their metadata files tell
+	warnings []string
+}
+
+func labels(inputLabel []string) []string {
+	return []string{"", inputLabel[0], inputLabel[1], "Delta"}
+}
+
+// compareReports reads both reports of the pair and collects the categories
+// and audits with differing scores.
+func compareReports(pair lighthouse.ReportPair) comparison {
+	c := comparison{formFactor: pair.FormFactor}
+
 	reports := []*parser.Report{}
 	{
-		for _, inputItem := range input {
+		for _, inputItem := range []string{pair.A, pair.B} {
 
 			data, err := ioutil.ReadFile(inputItem)
 			if err != nil {
@@ -91,11 +213,20 @@ func compare(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	// output table data
-	data := [][]string{}
+	{
+		metas := []*lighthouse.Metadata{}
+		for _, inputItem := range []string{pair.A, pair.B} {
+			meta, err := lighthouse.ReadMetadata(inputItem)
+			if err != nil {
+				break
+			}
+			metas = append(metas, meta)
+		}
 
-	// table data that works in markdown, without ANSII escape sequences
-	markdownData := [][]string{}
+		if len(metas) == 2 {
+			c.warnings = lighthouse.DiffSettings(metas[0].Settings, metas[1].Settings)
+		}
+	}
 
 	// Compare main category scores
 	for catID, catA := range reports[0].Categories {
@@ -129,9 +260,9 @@ func compare(cmd *cobra.Command, args []string) {
 			markdownDelta,
 		}
 
-		data = append(data, row)
+		c.data = append(c.data, row)
 
-		markdownData = append(markdownData, markdownRow)
+		c.markdownData = append(c.markdownData, markdownRow)
 
 		// Compare individual audits
 		for _, auditRef := range catA.AuditRefs {
@@ -174,86 +305,60 @@ func compare(cmd *cobra.Command, args []string) {
 				markdownDelta,
 			}
 
-			data = append(data, row)
+			c.data = append(c.data, row)
 
-			markdownData = append(markdownData, markdownRow)
+			c.markdownData = append(c.markdownData, markdownRow)
 		}
 	}
 
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetAutoWrapText(false)
-	labels := []string{"", inputLabel[0], inputLabel[1], "Delta"}
-	table.SetHeader(labels)
-
-	for _, v := range data {
-		table.Append(v)
-	}
-
-	if len(data) > 0 {
-		table.Render()
-	} else if len(inputLabel) == 2 && inputLabel[0] != "" && inputLabel[1] != "" {
-		fmt.Printf("The comparison of lighthouse reports between `%s` and `%s` showed no difference.\n", inputLabel[0], inputLabel[1])
-	}
+	return c
+}
 
-	// comment to Github
-	var owner string
-	var repo string
-	var token string
-	var issue int
-	{
-		owner, err = cmd.Flags().GetString("github-owner")
-		if err != nil {
-			fmt.Println("Error while reading --github-owner flag:")
-			fmt.Println(err)
-			os.Exit(1)
+// commentBody renders the comparisons as a markdown comment, with a table per
+// form factor. Settings warnings and reports missing on one side are listed
+// even if no scores differ. It is empty if there is nothing to report.
+func commentBody(comparisons []comparison, labels []string, missing []string) string {
+	var body string
+	for _, c := range comparisons {
+		if len(c.markdownData) == 0 && len(c.warnings) == 0 {
+			continue
 		}
 
-		repo, err = cmd.Flags().GetString("github-repo")
-		if err != nil {
-			fmt.Println("Error while reading --github-repo flag:")
-			fmt.Println(err)
-			os.Exit(1)
+		if c.formFactor != "" {
+			body += "\n#### " + strings.Title(c.formFactor) + "\n\n"
 		}
 
-		token, err = cmd.Flags().GetString("github-token")
-		if err != nil {
-			fmt.Println("Error while reading --github-token flag:")
-			fmt.Println(err)
-			os.Exit(1)
+		if len(c.markdownData) > 0 {
+			var buf bytes.Buffer
+			markdownTable := tablewriter.NewWriter(&buf)
+			markdownTable.SetHeader(labels)
+			markdownTable.SetAutoWrapText(false)
+			markdownTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			markdownTable.SetCenterSeparator("|")
+			markdownTable.AppendBulk(c.markdownData)
+			markdownTable.Render()
+			body += buf.String()
+		} else {
+			body += "No scores differ.\n"
 		}
 
-		issue, err = cmd.Flags().GetInt("github-issue")
-		if err != nil {
-			fmt.Println("Error while reading --github-issue flag:")
-			fmt.Println(err)
-			os.Exit(1)
+		if len(c.warnings) > 0 {
+			body += "\n⚠️ The reports were created with different settings:\n\n"
+			for _, w := range c.warnings {
+				body += "- `" + w + "`\n"
+			}
 		}
+	}
 
-		if owner != "" && repo != "" && token != "" && issue != 0 {
-			var body string
-			if len(markdownData) > 0 {
-				var buf bytes.Buffer
-				markdownTable := tablewriter.NewWriter(&buf)
-				markdownTable.SetHeader(labels)
-				markdownTable.SetAutoWrapText(false)
-				markdownTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-				markdownTable.SetCenterSeparator("|")
-				markdownTable.AppendBulk(markdownData)
-				markdownTable.Render()
-
-				body = "Comparison of lighthouse reports:\n\n"
-				body += buf.String()
-			}
+	for _, f := range missing {
+		body += "\n⚠️ There is a " + f + " report for only one side, it is not compared.\n"
+	}
 
-			if body != "" {
-				err = commenter.AddComment(token, owner, repo, body, issue)
-				if err != nil {
-					fmt.Println(err)
-				}
-			}
-		}
+	if body == "" {
+		return ""
 	}
 
+	return "Comparison of lighthouse reports:\n\n" + body
 }
 
 func validateFlags(cmd *cobra.Command, args []string) error {
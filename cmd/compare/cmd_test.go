@@ -0,0 +1,57 @@
+package compare
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCommentBody checks that warnings are commented even if no scores
+// differ.
+func TestCommentBody(t *testing.T) {
+	labels := []string{"Category", "Base", "Head"}
+	scores := [][]string{{"Performance", "82", "86"}}
+
+	testCases := []struct {
+		name        string
+		comparisons []comparison
+		missing     []string
+		expected    []string
+	}{
+		{
+			name:        "no differences",
+			comparisons: []comparison{{formFactor: "desktop"}},
+			expected:    nil,
+		},
+		{
+			name:        "scores differ",
+			comparisons: []comparison{{formFactor: "desktop", markdownData: scores}},
+			expected:    []string{"#### Desktop", "| Performance |   82 |   86 |"},
+		},
+		{
+			name:        "report missing on one side",
+			comparisons: []comparison{{formFactor: "desktop"}},
+			missing:     []string{"mobile"},
+			expected:    []string{"There is a mobile report for only one side"},
+		},
+		{
+			name: "settings differ",
+			comparisons: []comparison{
+				{formFactor: "desktop", markdownData: scores},
+				{formFactor: "mobile", warnings: []string{"runs: 1 != 3"}},
+			},
+			expected: []string{"#### Desktop", "#### Mobile", "No scores differ.", "- `runs: 1 != 3`"},
+		},
+	}
+
+	for _, tc := range testCases {
+		body := commentBody(tc.comparisons, labels, tc.missing)
+		if tc.expected == nil && body != "" {
+			t.Errorf("%s: expected no comment, got:\n%s", tc.name, body)
+		}
+		for _, e := range tc.expected {
+			if !strings.Contains(body, e) {
+				t.Errorf("%s: expected %q in:\n%s", tc.name, e, body)
+			}
+		}
+	}
+}
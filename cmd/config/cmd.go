@@ -0,0 +1,93 @@
+// Package config provides the `config` command to work with
+// lighthouse-keeper.yaml config files.
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/lighthouse-keeper/service/config"
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+)
+
+// Cmd is our cobra command
+var Cmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with " + config.DefaultFile + " config files",
+}
+
+// ValidateCmd checks a config file.
+var ValidateCmd = &cobra.Command{
+	Use:     "validate",
+	Short:   "Check a config file and print the audits it declares",
+	PreRunE: validateFlags,
+	Run:     validate,
+	Example: `
+  lighthouse-keeper config validate
+
+  lighthouse-keeper config validate --config ci/` + config.DefaultFile,
+}
+
+func init() {
+	ValidateCmd.Flags().StringP("config", "", config.DefaultFile, "Config file to check")
+
+	Cmd.AddCommand(ValidateCmd)
+}
+
+func validate(cmd *cobra.Command, args []string) {
+	path, err := cmd.Flags().GetString("config")
+	if err != nil {
+		fmt.Println("Error while reading --config flag:")
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	c, err := config.Read(path)
+	if config.IsInvalidConfigError(err) {
+		fmt.Println("Invalid config file:")
+		fmt.Println(err)
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Printf("Error while reading file %q:\n", path)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s is valid\n", path)
+
+	entries := c.Entries()
+	if len(entries) == 0 {
+		fmt.Println("It declares no sites, URLs have to be given with --url or another flag")
+		return
+	}
+
+	formFactors := c.FormFactors
+	if len(formFactors) == 0 {
+		formFactors = []string{lighthouse.FormFactorDesktop}
+	}
+	presets := c.Presets
+	if len(presets) == 0 {
+		presets = []string{"none"}
+	}
+
+	fmt.Printf("URLs: %d\n", len(entries))
+	fmt.Printf("Form factors: %s\n", strings.Join(formFactors, ", "))
+	fmt.Printf("Presets: %s\n", strings.Join(presets, ", "))
+	fmt.Printf("Audits: %d\n", c.Audits())
+}
+
+func validateFlags(cmd *cobra.Command, args []string) error {
+	path, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "could not read value for --config flag")
+	}
+	if path == "" {
+		return microerror.Maskf(invalidFlagsError, "please specify the file to check using the --config flag")
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/giantswarm/lighthouse-keeper/cmd/audit"
 	"github.com/giantswarm/lighthouse-keeper/cmd/compare"
+	"github.com/giantswarm/lighthouse-keeper/cmd/config"
 	"github.com/giantswarm/lighthouse-keeper/cmd/view"
 )
 
@@ -19,6 +20,7 @@ var RootCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(audit.Cmd)
 	RootCmd.AddCommand(compare.Cmd)
+	RootCmd.AddCommand(config.Cmd)
 	RootCmd.AddCommand(view.Cmd)
 }
 
@@ -0,0 +1,167 @@
+// Package compose manages docker-compose stacks providing the site under
+// test.
+package compose
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Config holds the settings to create a Compose.
+type Config struct {
+	// Binary is the container command line client, "docker" or "podman".
+	// Defaults to "docker". If it has no compose subcommand, the
+	// standalone docker-compose or podman-compose binary is used.
+	Binary string
+	// File is the path of the compose file.
+	File string
+	// Project is the compose project name, used to keep the stack apart
+	// from others on the same host.
+	Project string
+}
+
+// Compose controls one compose project.
+type Compose struct {
+	command []string
+	binary  string
+	file    string
+	project string
+}
+
+// New returns a Compose for the given file and project.
+func New(config Config) (*Compose, error) {
+	if config.Binary == "" {
+		config.Binary = "docker"
+	}
+	if config.File == "" {
+		return nil, microerror.Maskf(invalidConfigError, "File must not be empty")
+	}
+	if config.Project == "" {
+		return nil, microerror.Maskf(invalidConfigError, "Project must not be empty")
+	}
+
+	c := &Compose{
+		binary:  config.Binary,
+		file:    config.File,
+		project: config.Project,
+	}
+
+	// prefer the compose plugin, fall back to the standalone binary
+	err := exec.Command(config.Binary, "compose", "version").Run()
+	if err == nil {
+		c.command = []string{config.Binary, "compose"}
+	} else {
+		standalone := config.Binary + "-compose"
+		_, err = exec.LookPath(standalone)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "neither '%s compose' nor %s is available", config.Binary, standalone)
+		}
+		c.command = []string{standalone}
+	}
+
+	return c, nil
+}
+
+// Up creates and starts all services in the background.
+func (c *Compose) Up(ctx context.Context) error {
+	_, err := c.run(ctx, "up", "--detach")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Down stops the stack and removes its containers, networks and volumes.
+func (c *Compose) Down(ctx context.Context) error {
+	_, err := c.run(ctx, "down", "--volumes", "--remove-orphans")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Logs returns the output of a service.
+func (c *Compose) Logs(ctx context.Context, service string) (string, error) {
+	out, err := c.run(ctx, "logs", "--no-color", service)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return out, nil
+}
+
+// Network returns the project network to reach the services in. name
+// selects one of the networks declared in the compose file, e.g. "backend"
+// for the project network <project>_backend. Without a name, the project's
+// only network or its default network is used.
+func (c *Compose) Network(ctx context.Context, name string) (string, error) {
+	command := exec.CommandContext(ctx, c.binary, "network", "ls",
+		"--filter", "label=com.docker.compose.project="+c.project,
+		"--format", "{{.Name}}")
+	out, err := command.Output()
+	if err != nil {
+		return "", microerror.Maskf(commandFailedError, "listing networks of project %s: %s", c.project, err)
+	}
+
+	return pickNetwork(c.project, name, strings.Fields(string(out)))
+}
+
+// pickNetwork selects the network of the project as described for
+// Network.
+func pickNetwork(project, name string, networks []string) (string, error) {
+	if len(networks) == 0 {
+		return "", microerror.Maskf(networkError, "project %s has no network", project)
+	}
+
+	// compose prefixes the names of the compose file with the project
+	declared := make([]string, len(networks))
+	for i, n := range networks {
+		declared[i] = strings.TrimPrefix(n, project+"_")
+	}
+	sort.Strings(declared)
+
+	if name != "" {
+		for _, n := range networks {
+			if n == name || n == project+"_"+name {
+				return n, nil
+			}
+		}
+		return "", microerror.Maskf(networkError, "project %s has no network %q, its networks are %s", project, name, strings.Join(declared, ", "))
+	}
+
+	if len(networks) == 1 {
+		return networks[0], nil
+	}
+	for _, n := range networks {
+		if n == project+"_default" {
+			return n, nil
+		}
+	}
+
+	return "", microerror.Maskf(networkError, "project %s has several networks, %s", project, strings.Join(declared, ", "))
+}
+
+// run executes a compose subcommand for the project and returns its output.
+func (c *Compose) run(ctx context.Context, args ...string) (string, error) {
+	fullArgs := append([]string{}, c.command[1:]...)
+	fullArgs = append(fullArgs, "--file", c.file, "--project-name", c.project)
+	fullArgs = append(fullArgs, args...)
+
+	command := exec.CommandContext(ctx, c.command[0], fullArgs...)
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	err := command.Run()
+	if err != nil {
+		return "", microerror.Maskf(commandFailedError, "%s: %s", strings.Join(c.command, " "), strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,90 @@
+package compose
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPickNetwork checks which project network the site is audited in.
+func TestPickNetwork(t *testing.T) {
+	testCases := []struct {
+		name          string
+		selected      string
+		networks      []string
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "only network",
+			networks: []string{"lhk_backend"},
+			expected: "lhk_backend",
+		},
+		{
+			name:     "default network",
+			networks: []string{"lhk_a", "lhk_default", "lhk_z"},
+			expected: "lhk_default",
+		},
+		{
+			name:          "several networks",
+			networks:      []string{"lhk_frontend", "lhk_backend"},
+			expectedError: "project lhk has several networks, backend, frontend",
+		},
+		{
+			name:     "selected by compose file name",
+			selected: "frontend",
+			networks: []string{"lhk_frontend", "lhk_backend"},
+			expected: "lhk_frontend",
+		},
+		{
+			name:     "selected by full name",
+			selected: "lhk_backend",
+			networks: []string{"lhk_frontend", "lhk_backend", "lhk_default"},
+			expected: "lhk_backend",
+		},
+		{
+			name:          "unknown network",
+			selected:      "db",
+			networks:      []string{"lhk_frontend", "lhk_backend"},
+			expectedError: `project lhk has no network "db", its networks are backend, frontend`,
+		},
+		{
+			name:          "no network",
+			expectedError: "project lhk has no network",
+		},
+	}
+
+	for _, tc := range testCases {
+		network, err := pickNetwork("lhk", tc.selected, tc.networks)
+		if tc.expectedError != "" {
+			if !IsNetworkError(err) || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Errorf("%s: expected network error %q, got %v", tc.name, tc.expectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tc.name, err)
+			continue
+		}
+		if network != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, network)
+		}
+	}
+}
+
+// TestNew checks the required settings.
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config Config
+	}{
+		{"missing file", Config{Project: "lhk"}},
+		{"missing project", Config{File: "docker-compose.yml"}},
+	}
+
+	for _, tc := range testCases {
+		_, err := New(tc.config)
+		if !IsInvalidConfigError(err) {
+			t.Errorf("%s: expected invalid config error, got %v", tc.name, err)
+		}
+	}
+}
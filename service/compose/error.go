@@ -0,0 +1,33 @@
+package compose
+
+import "github.com/giantswarm/microerror"
+
+// invalidConfigError is used when the compose configuration is invalid
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfigError asserts invalidConfigError
+func IsInvalidConfigError(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+// commandFailedError is used when a compose command fails
+var commandFailedError = &microerror.Error{
+	Kind: "commandFailedError",
+}
+
+// IsCommandFailedError asserts commandFailedError
+func IsCommandFailedError(err error) bool {
+	return microerror.Cause(err) == commandFailedError
+}
+
+// networkError is used when the network of the project can't be determined
+var networkError = &microerror.Error{
+	Kind: "networkError",
+}
+
+// IsNetworkError asserts networkError
+func IsNetworkError(err error) bool {
+	return microerror.Cause(err) == networkError
+}
@@ -0,0 +1,468 @@
+// Package config reads lighthouse-keeper.yaml, which declares the audit
+// matrix of a project: the sites and paths to audit with each form factor
+// and emulation preset, and the settings shared by all audits.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+	"github.com/giantswarm/lighthouse-keeper/service/urllist"
+)
+
+// DefaultFile is the conventional name of the config file.
+const DefaultFile = "lighthouse-keeper.yaml"
+
+// envNameExpr matches the names of environment variables.
+var envNameExpr = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Config is the content of a config file. Unset values are empty.
+type Config struct {
+	// Sites are the sites to audit.
+	Sites []Site
+	// FormFactors are the form factors to audit each URL with.
+	FormFactors []string
+	// Presets are the emulation presets to audit each URL with.
+	Presets []string
+	// Runs is the number of lighthouse runs per URL and form factor.
+	Runs int
+	// Auth holds the credentials sent with every request.
+	Auth Auth
+	// Thresholds are the minimum category scores of every report.
+	Thresholds lighthouse.Thresholds
+}
+
+// Site is a site to audit.
+type Site struct {
+	// Name is the report name prefix. If empty, reports are named like
+	// those of URLs given without a name.
+	Name string
+	// URL is the absolute base URL of the site.
+	URL string
+	// Paths are audited relative to URL. If empty, URL itself is
+	// audited.
+	Paths []string
+}
+
+// Auth holds the credentials sent with every request.
+type Auth struct {
+	// Headers are extra headers as "Name: value".
+	Headers []string
+	// CookieFile is a Netscape cookies.txt or JSON file.
+	CookieFile string
+	// BasicAuthEnv names the environment variable holding basic auth
+	// credentials as user:password. The file itself must not hold
+	// secrets.
+	BasicAuthEnv string
+	// LoginScript is a Puppeteer script run before the audit.
+	LoginScript string
+}
+
+// Entries returns the URLs of all sites with their report names. Sites with
+// several paths name their reports <name>-<path slug>.
+func (c *Config) Entries() []urllist.Entry {
+	entries := []urllist.Entry{}
+	for _, s := range c.Sites {
+		if len(s.Paths) == 0 {
+			entries = append(entries, urllist.Entry{URL: s.URL, Name: s.Name})
+			continue
+		}
+
+		for _, p := range s.Paths {
+			entry := urllist.Entry{URL: strings.TrimSuffix(s.URL, "/") + p}
+			if s.Name != "" && len(s.Paths) == 1 {
+				entry.Name = s.Name
+			} else if s.Name != "" {
+				entry.Name = s.Name + "-" + urllist.Slug(entry.URL)
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// Audits returns the number of audits the config declares: each URL with
+// each preset and each form factor the preset applies to.
+func (c *Config) Audits() int {
+	formFactors := len(c.FormFactors)
+	if formFactors == 0 {
+		formFactors = 1
+	}
+
+	perURL := 0
+	for _, name := range c.Presets {
+		preset, err := lighthouse.GetPreset(name)
+		if err == nil && preset.FormFactor != "" {
+			perURL++
+		} else {
+			perURL += formFactors
+		}
+	}
+	if len(c.Presets) == 0 {
+		perURL = formFactors
+	}
+
+	return len(c.Entries()) * perURL
+}
+
+// Read reads and validates a config file. Files referred to by the config
+// are resolved relative to its directory.
+func Read(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	config, err := Parse(data)
+	if err != nil {
+		return nil, microerror.Maskf(err, "%s", path)
+	}
+
+	dir := filepath.Dir(path)
+	for _, p := range []*string{&config.Auth.CookieFile, &config.Auth.LoginScript} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+
+	return config, nil
+}
+
+// Parse parses and validates the content of a config file. All problems
+// are reported at once, one per line, with the line they refer to.
+func Parse(data []byte) (*Config, error) {
+	var document yaml.Node
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%s", strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	d := &decoder{}
+	config := &Config{}
+	// an empty file has no content
+	if len(document.Content) > 0 {
+		config = d.config(document.Content[0])
+	}
+	if len(d.problems) > 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%s", strings.Join(d.problems, "\n"))
+	}
+
+	return config, nil
+}
+
+// decoder converts the parsed document into a Config, collecting problems
+// instead of stopping at the first one.
+type decoder struct {
+	problems []string
+}
+
+func (d *decoder) errorf(line int, format string, args ...interface{}) {
+	d.problems = append(d.problems, fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, args...)))
+}
+
+func (d *decoder) config(root *yaml.Node) *Config {
+	config := &Config{}
+	if !d.expect(root, yaml.MappingNode, "the config") {
+		return config
+	}
+
+	presetsLine := 0
+	for _, p := range pairs(root) {
+		switch p.key {
+		case "sites":
+			config.Sites = d.sites(p.value)
+		case "formFactors":
+			config.FormFactors = d.formFactors(p.value)
+		case "presets":
+			config.Presets = d.presets(p.value)
+			presetsLine = p.line
+		case "runs":
+			config.Runs = d.runs(p.value)
+		case "auth":
+			config.Auth = d.auth(p.value)
+		case "thresholds":
+			config.Thresholds = d.thresholds(p.value)
+		default:
+			d.errorf(p.line, "unknown key %q, expected sites, formFactors, presets, runs, auth or thresholds", p.key)
+		}
+	}
+
+	// a preset for a specific form factor is only audited with that one,
+	// which must be among the declared ones
+	for _, name := range config.Presets {
+		preset, err := lighthouse.GetPreset(name)
+		if err == nil && preset.FormFactor != "" && len(config.FormFactors) > 0 && !contains(config.FormFactors, preset.FormFactor) {
+			d.errorf(presetsLine, "preset %q is for form factor %q, which is not in formFactors", name, preset.FormFactor)
+		}
+	}
+
+	return config
+}
+
+func (d *decoder) sites(node *yaml.Node) []Site {
+	sites := []Site{}
+	if !d.expect(node, yaml.SequenceNode, "sites") {
+		return sites
+	}
+
+	for _, item := range items(node) {
+		site := Site{}
+		switch item.Kind {
+		case yaml.ScalarNode:
+			site.URL = scalarValue(item)
+		case yaml.MappingNode:
+			for _, p := range pairs(item) {
+				switch p.key {
+				case "name":
+					site.Name = d.scalar(p.value, "name")
+				case "url":
+					site.URL = d.scalar(p.value, "url")
+				case "paths":
+					site.Paths = d.paths(p.value)
+				default:
+					d.errorf(p.line, "unknown key %q, expected name, url or paths", p.key)
+				}
+			}
+		default:
+			d.errorf(item.Line, "expected a URL or a mapping with name, url and paths")
+			continue
+		}
+
+		if site.URL == "" {
+			d.errorf(item.Line, "site is missing a url")
+		} else if u, err := url.Parse(site.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			d.errorf(item.Line, "site url %q must be an absolute http(s) URL", site.URL)
+		}
+		sites = append(sites, site)
+	}
+
+	return sites
+}
+
+func (d *decoder) paths(node *yaml.Node) []string {
+	paths := d.list(node, "paths")
+	for _, p := range paths {
+		if !strings.HasPrefix(p, "/") {
+			d.errorf(node.Line, "path %q must start with '/'", p)
+		}
+	}
+
+	return paths
+}
+
+func (d *decoder) formFactors(node *yaml.Node) []string {
+	values := d.list(node, "formFactors")
+	if len(values) == 0 {
+		return nil
+	}
+
+	formFactors, err := lighthouse.ParseFormFactors(strings.Join(values, ","))
+	if err != nil {
+		d.errorf(node.Line, "%s", err)
+		return nil
+	}
+
+	return formFactors
+}
+
+func (d *decoder) presets(node *yaml.Node) []string {
+	presets := d.list(node, "presets")
+	for _, p := range presets {
+		_, err := lighthouse.GetPreset(p)
+		if err != nil {
+			d.errorf(node.Line, "%s", err)
+		}
+	}
+
+	return presets
+}
+
+func (d *decoder) runs(node *yaml.Node) int {
+	value := d.scalar(node, "runs")
+	runs, err := strconv.Atoi(value)
+	if err != nil || runs < 1 {
+		d.errorf(node.Line, "runs must be a number of 1 or greater, not %q", value)
+		return 0
+	}
+
+	return runs
+}
+
+func (d *decoder) auth(node *yaml.Node) Auth {
+	auth := Auth{}
+	if !d.expect(node, yaml.MappingNode, "auth") {
+		return auth
+	}
+
+	for _, p := range pairs(node) {
+		switch p.key {
+		case "headers":
+			if !d.expect(p.value, yaml.MappingNode, "headers") {
+				continue
+			}
+			for _, h := range pairs(p.value) {
+				header := h.key + ": " + d.scalar(h.value, h.key)
+				_, _, err := lighthouse.ParseHeader(header)
+				if err != nil {
+					d.errorf(h.line, "%s", err)
+				}
+				auth.Headers = append(auth.Headers, header)
+			}
+		case "cookieFile":
+			auth.CookieFile = d.scalar(p.value, "cookieFile")
+		case "basicAuthEnv":
+			auth.BasicAuthEnv = d.scalar(p.value, "basicAuthEnv")
+			if !envNameExpr.MatchString(auth.BasicAuthEnv) {
+				d.errorf(p.line, "basicAuthEnv must be the name of an environment variable, not %q", auth.BasicAuthEnv)
+			}
+		case "basicAuth":
+			d.errorf(p.line, "credentials must not be stored in the config file, name the environment variable holding them with basicAuthEnv")
+		case "loginScript":
+			auth.LoginScript = d.scalar(p.value, "loginScript")
+		default:
+			d.errorf(p.line, "unknown key %q, expected headers, cookieFile, basicAuthEnv or loginScript", p.key)
+		}
+	}
+
+	return auth
+}
+
+func (d *decoder) thresholds(node *yaml.Node) lighthouse.Thresholds {
+	thresholds := lighthouse.Thresholds{}
+	if !d.expect(node, yaml.MappingNode, "thresholds") {
+		return thresholds
+	}
+
+	for _, p := range pairs(node) {
+		value := d.scalar(p.value, p.key)
+		score, err := strconv.ParseFloat(value, 64)
+		if err != nil || score < 0 || score > 100 {
+			d.errorf(p.line, "threshold of %s must be a score between 0 and 100, not %q", p.key, value)
+			continue
+		}
+		thresholds[p.key] = score
+	}
+
+	return thresholds
+}
+
+// list returns the values of a list of scalars. A single scalar is a list
+// of one.
+func (d *decoder) list(node *yaml.Node, key string) []string {
+	node = resolve(node)
+	if node.Kind == yaml.ScalarNode {
+		if value := scalarValue(node); value != "" {
+			return []string{value}
+		}
+		return nil
+	}
+	if !d.expect(node, yaml.SequenceNode, key) {
+		return nil
+	}
+
+	values := []string{}
+	for _, item := range items(node) {
+		values = append(values, d.scalar(item, key))
+	}
+
+	return values
+}
+
+func (d *decoder) scalar(node *yaml.Node, key string) string {
+	if !d.expect(node, yaml.ScalarNode, key) {
+		return ""
+	}
+
+	return scalarValue(resolve(node))
+}
+
+// expect records a problem if the node is not of the given kind.
+func (d *decoder) expect(node *yaml.Node, kind yaml.Kind, name string) bool {
+	node = resolve(node)
+	if node.Kind != kind {
+		d.errorf(node.Line, "%s must be a %s, not a %s", name, kindName(kind), kindName(node.Kind))
+		return false
+	}
+
+	return true
+}
+
+// pair is an entry of a mapping node.
+type pair struct {
+	key   string
+	line  int
+	value *yaml.Node
+}
+
+// pairs returns the entries of a mapping node in document order.
+func pairs(node *yaml.Node) []pair {
+	node = resolve(node)
+	list := []pair{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		list = append(list, pair{key: key.Value, line: key.Line, value: resolve(node.Content[i+1])})
+	}
+
+	return list
+}
+
+// items returns the items of a sequence node.
+func items(node *yaml.Node) []*yaml.Node {
+	node = resolve(node)
+	list := []*yaml.Node{}
+	for _, item := range node.Content {
+		list = append(list, resolve(item))
+	}
+
+	return list
+}
+
+// resolve returns the node an alias refers to.
+func resolve(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		return node.Alias
+	}
+
+	return node
+}
+
+// scalarValue returns the value of a scalar node, empty for null.
+func scalarValue(node *yaml.Node) string {
+	if node.ShortTag() == "!!null" {
+		return ""
+	}
+
+	return node.Value
+}
+
+// kindName names the kind of a node in problems.
+func kindName(kind yaml.Kind) string {
+	switch kind {
+	case yaml.SequenceNode:
+		return "list"
+	case yaml.MappingNode:
+		return "mapping"
+	}
+
+	return "value"
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
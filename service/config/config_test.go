@@ -0,0 +1,201 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/lighthouse-keeper/service/lighthouse"
+	"github.com/giantswarm/lighthouse-keeper/service/urllist"
+)
+
+// TestParse checks decoding and validation of config files.
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name             string
+		input            string
+		expectedConfig   *Config
+		expectedEntries  []urllist.Entry
+		expectedAudits   int
+		expectedProblems []string
+	}{
+		{
+			name:            "empty",
+			input:           "# nothing yet\n",
+			expectedConfig:  &Config{},
+			expectedEntries: []urllist.Entry{},
+		},
+		{
+			name: "full",
+			input: `sites:
+  - name: shop
+    url: https://shop.example.com/
+    paths:
+      - /
+      - /cart?step=1
+  - name: blog
+    url: https://blog.example.com
+    paths: [/latest]
+  - https://example.com/
+formFactors: both
+presets: [mobile-slow-4g, no-throttling]
+runs: 3
+auth:
+  headers:
+    X-Token: abc
+  cookieFile: /etc/cookies.txt
+  basicAuthEnv: SHOP_AUTH
+  loginScript: login.js
+thresholds:
+  performance: 90
+  accessibility: 95.5
+`,
+			expectedConfig: &Config{
+				Sites: []Site{
+					{Name: "shop", URL: "https://shop.example.com/", Paths: []string{"/", "/cart?step=1"}},
+					{Name: "blog", URL: "https://blog.example.com", Paths: []string{"/latest"}},
+					{URL: "https://example.com/"},
+				},
+				FormFactors: []string{lighthouse.FormFactorDesktop, lighthouse.FormFactorMobile},
+				Presets:     []string{"mobile-slow-4g", "no-throttling"},
+				Runs:        3,
+				Auth: Auth{
+					Headers:      []string{"X-Token: abc"},
+					CookieFile:   "/etc/cookies.txt",
+					BasicAuthEnv: "SHOP_AUTH",
+					LoginScript:  "login.js",
+				},
+				Thresholds: lighthouse.Thresholds{"performance": 90, "accessibility": 95.5},
+			},
+			expectedEntries: []urllist.Entry{
+				{URL: "https://shop.example.com/", Name: "shop-index"},
+				{URL: "https://shop.example.com/cart?step=1", Name: "shop-cart-step-1"},
+				{URL: "https://blog.example.com/latest", Name: "blog"},
+				{URL: "https://example.com/"},
+			},
+			// mobile-slow-4g only with mobile, no-throttling with both
+			expectedAudits: 12,
+		},
+		{
+			name: "schema errors",
+			input: `sites:
+  - name: shop
+    paths: [about]
+  - ftp://example.com/
+  - [nested]
+formFactors: [desktop, tablet]
+presets: fast
+runs: many
+auth:
+  headers:
+    Bad Name: x
+  password: secret
+  basicAuth: user:secret
+  basicAuthEnv: shop-auth
+thresholds:
+  seo: 120
+color: blue
+`,
+			expectedProblems: []string{
+				`line 3: path "about" must start with '/'`,
+				"line 2: site is missing a url",
+				`line 4: site url "ftp://example.com/" must be an absolute http(s) URL`,
+				"line 5: expected a URL or a mapping with name, url and paths",
+				`line 6: unknown form factor "tablet"`,
+				`line 7: unknown preset "fast"`,
+				`line 8: runs must be a number of 1 or greater, not "many"`,
+				`line 11: header name "Bad Name" must not contain whitespace`,
+				`line 12: unknown key "password", expected headers, cookieFile, basicAuthEnv or loginScript`,
+				"line 13: credentials must not be stored in the config file",
+				`line 14: basicAuthEnv must be the name of an environment variable, not "shop-auth"`,
+				"line 16: threshold of seo must be a score between 0 and 100",
+				`line 17: unknown key "color"`,
+			},
+		},
+		{
+			name:             "preset for another form factor",
+			input:            "formFactors: [desktop]\npresets: [no-throttling, mobile-slow-4g]\n",
+			expectedProblems: []string{`line 2: preset "mobile-slow-4g" is for form factor "mobile", which is not in formFactors`},
+		},
+		{
+			name:             "wrong kinds",
+			input:            "sites: https://example.com/\nauth: [token]\n",
+			expectedProblems: []string{"line 1: sites must be a list, not a value", "line 2: auth must be a mapping, not a list"},
+		},
+		{
+			name:             "syntax error",
+			input:            "sites:\n\t- https://example.com/\n",
+			expectedProblems: []string{"line 2: found character that cannot start any token"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := Parse([]byte(tc.input))
+			if len(tc.expectedProblems) > 0 {
+				if !IsInvalidConfigError(err) {
+					t.Fatalf("expected invalid config error, got %#v", err)
+				}
+				problems := strings.Split(err.Error(), "\n")
+				if len(problems) != len(tc.expectedProblems) {
+					t.Errorf("expected %d problems, got %q", len(tc.expectedProblems), problems)
+				}
+				for _, p := range tc.expectedProblems {
+					if !strings.Contains(err.Error(), p) {
+						t.Errorf("expected %q in %q", p, err.Error())
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+
+			if !reflect.DeepEqual(config, tc.expectedConfig) {
+				t.Errorf("expected %+v, got %+v", tc.expectedConfig, config)
+			}
+			if !reflect.DeepEqual(config.Entries(), tc.expectedEntries) {
+				t.Errorf("expected entries %+v, got %+v", tc.expectedEntries, config.Entries())
+			}
+			if config.Audits() != tc.expectedAudits {
+				t.Errorf("expected %d audits, got %d", tc.expectedAudits, config.Audits())
+			}
+		})
+	}
+}
+
+// TestRead checks that files referred to by the config are resolved
+// relative to it.
+func TestRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, DefaultFile)
+	err = ioutil.WriteFile(path, []byte("auth:\n  cookieFile: cookies.txt\n  loginScript: /abs/login.js\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Auth.CookieFile != filepath.Join(dir, "cookies.txt") || config.Auth.LoginScript != "/abs/login.js" {
+		t.Errorf("unexpected paths %+v", config.Auth)
+	}
+
+	err = ioutil.WriteFile(path, []byte("runs: 0\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Read(path)
+	if !IsInvalidConfigError(err) || !strings.Contains(err.Error(), path) {
+		t.Errorf("expected an invalid config error mentioning the file, got %#v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package config
+
+import "github.com/giantswarm/microerror"
+
+// invalidConfigError is used when a config file can't be parsed or doesn't
+// match the schema
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfigError asserts invalidConfigError
+func IsInvalidConfigError(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
@@ -0,0 +1,344 @@
+// Package crawler discovers the pages of a site by following its links.
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Defaults for crawling.
+const (
+	DefaultDepth     = 2
+	DefaultMaxPages  = 50
+	DefaultUserAgent = "lighthouse-keeper"
+)
+
+// maxPageSize is the maximum number of bytes read from a page.
+const maxPageSize = 5 * 1024 * 1024
+
+// Config describes a crawl.
+type Config struct {
+	// Start is the URL to start crawling at. Only pages of the same
+	// origin are crawled.
+	Start string
+	// Depth is the maximum number of links followed from the start page.
+	Depth int
+	// MaxPages is the maximum number of pages returned. Zero means
+	// DefaultMaxPages.
+	MaxPages int
+	// Client is used for all requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// UserAgent is sent with requests and used to pick the robots.txt
+	// rules. Defaults to DefaultUserAgent.
+	UserAgent string
+	// Output receives a line per skipped page, if set.
+	Output io.Writer
+}
+
+type page struct {
+	url   *url.URL
+	depth int
+}
+
+// Crawl fetches the start page and follows same-origin links breadth first,
+// respecting robots.txt. Tracking parameters like utm_source are removed
+// from links. Pages whose paths only differ in ID-like segments, like
+// /blog/123 and /blog/456, form a group of which only the first page found
+// is crawled and returned. It returns the URLs of the HTML pages
+// found, starting with the start page.
+func Crawl(ctx context.Context, config Config) ([]string, error) {
+	if config.MaxPages == 0 {
+		config.MaxPages = DefaultMaxPages
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = DefaultUserAgent
+	}
+	if config.Output == nil {
+		config.Output = ioutil.Discard
+	}
+	if config.Depth < 0 || config.MaxPages < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "depth and max pages must not be negative")
+	}
+
+	start, err := url.Parse(config.Start)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		return nil, microerror.Maskf(invalidConfigError, "start URL %q must be an absolute http(s) URL", config.Start)
+	}
+	start.Fragment = ""
+	if start.Path == "" {
+		start.Path = "/"
+	}
+
+	c := &crawler{config: config, origin: origin(start)}
+	c.robots, err = c.fetchRobots(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	found := []string{}
+	seen := map[string]bool{GroupKey(start): true}
+	queue := []page{{url: start}}
+
+	for len(queue) > 0 && len(found) < config.MaxPages {
+		if ctx.Err() != nil {
+			return nil, microerror.Mask(ctx.Err())
+		}
+
+		p := queue[0]
+		queue = queue[1:]
+
+		if !c.robots.allowed(p.url.RequestURI()) {
+			fmt.Fprintf(config.Output, "Skipping %s, disallowed by robots.txt\n", p.url)
+			continue
+		}
+
+		final, links, err := c.fetch(ctx, p.url)
+		if err != nil {
+			if len(found) == 0 && p.depth == 0 {
+				return nil, microerror.Maskf(startPageError, "%s", err)
+			}
+			fmt.Fprintf(config.Output, "Skipping %s: %s\n", p.url, err)
+			continue
+		}
+		found = append(found, final.String())
+
+		if p.depth >= config.Depth {
+			continue
+		}
+		for _, link := range links {
+			if origin(link) != c.origin {
+				continue
+			}
+			link.Fragment = ""
+			removeTracking(link)
+			key := GroupKey(link)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			queue = append(queue, page{url: link, depth: p.depth + 1})
+		}
+	}
+
+	return found, nil
+}
+
+type crawler struct {
+	config Config
+	origin string
+	robots *robots
+}
+
+// fetchRobots reads the robots.txt of the origin. A missing or unreadable
+// robots.txt allows everything.
+func (c *crawler) fetchRobots(ctx context.Context) (*robots, error) {
+	response, err := c.get(ctx, c.origin+"/robots.txt")
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, microerror.Mask(ctx.Err())
+		}
+		return &robots{}, nil
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return &robots{}, nil
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(response.Body, maxPageSize))
+	if err != nil {
+		return &robots{}, nil
+	}
+
+	return parseRobots(string(data), c.config.UserAgent), nil
+}
+
+// fetch requests a page and returns its URL after redirects and the links
+// found in it. Pages that aren't HTML or didn't load are errors.
+func (c *crawler) fetch(ctx context.Context, u *url.URL) (*url.URL, []*url.URL, error) {
+	response, err := c.get(ctx, u.String())
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, nil, microerror.Newf("got status %s", response.Status)
+	}
+	if !strings.Contains(response.Header.Get("Content-Type"), "text/html") {
+		return nil, nil, microerror.Newf("not an HTML page")
+	}
+
+	final := response.Request.URL
+	if origin(final) != c.origin {
+		return nil, nil, microerror.Newf("redirected to %s", final)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(response.Body, maxPageSize))
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	return final, Links(final, string(data)), nil
+}
+
+func (c *crawler) get(ctx context.Context, u string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	request.Header.Set("User-Agent", c.config.UserAgent)
+
+	response, err := c.config.Client.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return response, nil
+}
+
+// Links returns the links of the anchor tags in the HTML document of the
+// page, resolved against the document's <base href> if it has one. Links
+// marked nofollow, links to fragments of the page itself and hrefs that
+// don't parse are skipped.
+func Links(page *url.URL, document string) []*url.URL {
+	base := page
+	hrefs := []string{}
+
+	z := html.NewTokenizer(strings.NewReader(document))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		t := z.Token()
+		switch t.DataAtom {
+		case atom.Base:
+			// only the first base element with an href counts
+			href, ok := attribute(t, "href")
+			if !ok || base != page {
+				continue
+			}
+			if u, err := page.Parse(strings.TrimSpace(href)); err == nil {
+				base = u
+			}
+		case atom.A:
+			if rel, _ := attribute(t, "rel"); strings.Contains(strings.ToLower(rel), "nofollow") {
+				continue
+			}
+			href, _ := attribute(t, "href")
+			href = strings.TrimSpace(href)
+			if href == "" || strings.HasPrefix(href, "#") {
+				continue
+			}
+			hrefs = append(hrefs, href)
+		}
+	}
+
+	links := []*url.URL{}
+	for _, href := range hrefs {
+		link, err := base.Parse(href)
+		if err != nil {
+			continue
+		}
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// attribute returns the value of the tag's attribute and whether it is set.
+func attribute(t html.Token, name string) (string, bool) {
+	for _, a := range t.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val, true
+		}
+	}
+
+	return "", false
+}
+
+// trackingExpr matches the names of query parameters that only track
+// campaigns and clicks.
+var trackingExpr = regexp.MustCompile(`^(?i)(utm_.+|gclid|fbclid)$`)
+
+// removeTracking removes tracking parameters like utm_source from the
+// query, keeping the order of the other parameters, so that links only
+// differing in them lead to the same page.
+func removeTracking(u *url.URL) {
+	if u.RawQuery == "" {
+		return
+	}
+
+	kept := []string{}
+	for _, parameter := range strings.Split(u.RawQuery, "&") {
+		name, err := url.QueryUnescape(strings.SplitN(parameter, "=", 2)[0])
+		if err == nil && trackingExpr.MatchString(name) {
+			continue
+		}
+		kept = append(kept, parameter)
+	}
+	u.RawQuery = strings.Join(kept, "&")
+}
+
+func origin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+var (
+	numberExpr = regexp.MustCompile(`^[0-9]+$`)
+	uuidExpr   = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	hashExpr   = regexp.MustCompile(`^(?i)[0-9a-f]*[0-9][0-9a-f]*$`)
+)
+
+// GroupKey returns the key of the group a URL belongs to. Path segments
+// that look like IDs, i.e. numbers, UUIDs and hex strings of 8 or more
+// characters, are replaced by a placeholder, and only the names of query
+// parameters are kept.
+func GroupKey(u *url.URL) string {
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, s := range segments {
+		if isID(s) {
+			segments[i] = ":id"
+		}
+	}
+	key := strings.Join(segments, "/")
+	if key == "" {
+		key = "/"
+	}
+
+	if u.RawQuery != "" {
+		names := []string{}
+		for name := range u.Query() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		key += "?" + strings.Join(names, "&")
+	}
+
+	return key
+}
+
+func isID(segment string) bool {
+	return numberExpr.MatchString(segment) ||
+		uuidExpr.MatchString(segment) ||
+		(len(segment) >= 8 && hashExpr.MatchString(segment))
+}
@@ -0,0 +1,207 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newSite returns a test site with the given pages. Each page links to the
+// given paths.
+func newSite(pages map[string][]string, robots string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" && robots != "" {
+			fmt.Fprint(w, robots)
+			return
+		}
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/about", http.StatusMovedPermanently)
+			return
+		}
+		if r.URL.Path == "/feed.xml" {
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprint(w, "<rss/>")
+			return
+		}
+		links, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html><body>")
+		for _, l := range links {
+			fmt.Fprintf(w, "<a class='link' href=\"%s\">%s</a>\n", l, l)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+}
+
+// TestCrawl checks which pages are found.
+func TestCrawl(t *testing.T) {
+	pages := map[string][]string{
+		"/":               {"/about", "/blog/", "https://other.example.com/", "/private/page", "/feed.xml", "/missing", "#top"},
+		"/about":          {"/team", "/about?utm_source=x&gclid=1"},
+		"/blog/":          {"/blog/123", "/blog/456?utm_source=x", "/blog/456", "/blog/789#comments", "/blog/?page=2&fbclid=1"},
+		"/blog/123":       {"/blog/"},
+		"/blog/456":       {},
+		"/team":           {"/team/deep"},
+		"/team/deep":      {},
+		"/private/page":   {},
+		"/blog/789":       {},
+		"/blog/123/reply": {},
+	}
+	site := newSite(pages, "User-agent: *\nDisallow: /private/\n")
+	defer site.Close()
+
+	testCases := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{
+			name:     "depth 2",
+			config:   Config{Depth: 2},
+			expected: []string{"/", "/about", "/blog/", "/team", "/blog/123", "/blog/?page=2"},
+		},
+		{
+			name:     "depth 0",
+			config:   Config{Depth: 0},
+			expected: []string{"/"},
+		},
+		{
+			name:     "max pages",
+			config:   Config{Depth: 3, MaxPages: 2},
+			expected: []string{"/", "/about"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc.config.Start = site.URL + "/"
+		tc.config.Client = site.Client()
+
+		found, err := Crawl(context.Background(), tc.config)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+			continue
+		}
+
+		paths := []string{}
+		for _, f := range found {
+			paths = append(paths, strings.TrimPrefix(f, site.URL))
+		}
+		if !reflect.DeepEqual(paths, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, paths)
+		}
+	}
+
+	_, err := Crawl(context.Background(), Config{Start: site.URL + "/missing", Client: site.Client()})
+	if !IsStartPageError(err) {
+		t.Errorf("expected start page error, got %v", err)
+	}
+}
+
+// TestLinks checks link extraction from HTML.
+func TestLinks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		document string
+		expected []string
+	}{
+		{
+			name: "anchors",
+			document: `<html><head><script>var a = '<a href="/script">';</script></head>
+<body>
+<!-- <a href="/commented">old</a> -->
+<A HREF=/upper>upper</A>
+<a title="x" href='single?a=1&amp;b=2'>single</a>
+<a href="/nofollow" rel="nofollow">no</a>
+<a name="anchor">no href</a>
+<a href="#top">top</a>
+<area href="/area">
+<textarea><a href="/text"></textarea>
+</body></html>`,
+			expected: []string{"https://example.com/upper", "https://example.com/blog/single?a=1&b=2"},
+		},
+		{
+			name: "base",
+			document: `<html><head><base href="/docs/"><base href="/ignored/"></head>
+<body><a href="intro">intro</a> <a href="/about">about</a> <a href="https://other.example.com/">other</a></body></html>`,
+			expected: []string{"https://example.com/docs/intro", "https://example.com/about", "https://other.example.com/"},
+		},
+		{
+			name:     "base without href",
+			document: `<base target="_blank"><a href="post">post</a>`,
+			expected: []string{"https://example.com/blog/post"},
+		},
+	}
+
+	page, err := url.Parse("https://example.com/blog/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range testCases {
+		links := []string{}
+		for _, l := range Links(page, tc.document) {
+			links = append(links, l.String())
+		}
+		if !reflect.DeepEqual(links, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, links)
+		}
+	}
+}
+
+// TestRemoveTracking checks that only tracking parameters are removed.
+func TestRemoveTracking(t *testing.T) {
+	testCases := []struct {
+		url      string
+		expected string
+	}{
+		{url: "https://example.com/?utm_source=news&utm_medium=email", expected: "https://example.com/"},
+		{url: "https://example.com/search?q=a&gclid=x&page=2&fbclid=y", expected: "https://example.com/search?q=a&page=2"},
+		{url: "https://example.com/?UTM_Campaign=x&utm=1", expected: "https://example.com/?utm=1"},
+		{url: "https://example.com/blog", expected: "https://example.com/blog"},
+	}
+
+	for _, tc := range testCases {
+		u, err := url.Parse(tc.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		removeTracking(u)
+		if u.String() != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.url, tc.expected, u.String())
+		}
+	}
+}
+
+// TestGroupKey checks grouping of URLs differing in IDs.
+func TestGroupKey(t *testing.T) {
+	testCases := []struct {
+		url      string
+		expected string
+	}{
+		{url: "https://example.com", expected: "/"},
+		{url: "https://example.com/blog/123", expected: "/blog/:id"},
+		{url: "https://example.com/users/550e8400-e29b-41d4-a716-446655440000/posts", expected: "/users/:id/posts"},
+		{url: "https://example.com/commit/3f2a9c1d", expected: "/commit/:id"},
+		{url: "https://example.com/decade/face", expected: "/decade/face"},
+		{url: "https://example.com/search?q=a&page=2", expected: "/search?page&q"},
+	}
+
+	for _, tc := range testCases {
+		u, err := url.Parse(tc.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key := GroupKey(u); key != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.url, tc.expected, key)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package crawler
+
+import "github.com/giantswarm/microerror"
+
+// invalidConfigError is used when the crawler configuration is invalid
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfigError asserts invalidConfigError
+func IsInvalidConfigError(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+// startPageError is used when the start page can't be crawled
+var startPageError = &microerror.Error{
+	Kind: "startPageError",
+}
+
+// IsStartPageError asserts startPageError
+func IsStartPageError(err error) bool {
+	return microerror.Cause(err) == startPageError
+}
@@ -0,0 +1,117 @@
+package crawler
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+)
+
+// robots holds the robots.txt rules applying to the crawler.
+type robots struct {
+	rules []robotsRule
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+	regexp  *regexp.Regexp
+}
+
+// parseRobots returns the rules of the group for the user agent, or of the
+// group for all user agents if there is no specific one. A specific group
+// without rules, or with only an empty Disallow, allows everything.
+func parseRobots(data string, userAgent string) *robots {
+	userAgent = strings.ToLower(userAgent)
+
+	var specific, general []robotsRule
+	var agents []string
+	inRules := false
+	matched := false
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		field := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		switch field {
+		case "user-agent":
+			// consecutive user-agent lines share a group
+			if inRules {
+				agents = nil
+				inRules = false
+			}
+			agent := strings.ToLower(value)
+			if agent == "" {
+				continue
+			}
+			agents = append(agents, agent)
+			if agent != "*" && strings.Contains(userAgent, agent) {
+				matched = true
+			}
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// an empty disallow allows everything
+				continue
+			}
+			rule := robotsRule{allow: field == "allow", pattern: value, regexp: robotsPattern(value)}
+			for _, a := range agents {
+				if a == "*" {
+					general = append(general, rule)
+				} else if strings.Contains(userAgent, a) {
+					specific = append(specific, rule)
+				}
+			}
+		}
+	}
+
+	if matched {
+		return &robots{rules: specific}
+	}
+
+	return &robots{rules: general}
+}
+
+// robotsPattern turns a path pattern with * wildcards and an optional $
+// end anchor into a regular expression.
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+
+	return regexp.MustCompile(expr)
+}
+
+// allowed returns whether the path, including the query, may be crawled.
+// The longest matching rule wins, allow wins ties.
+func (r *robots) allowed(path string) bool {
+	allowed := true
+	length := -1
+	for _, rule := range r.rules {
+		if !rule.regexp.MatchString(path) {
+			continue
+		}
+		if len(rule.pattern) > length || (len(rule.pattern) == length && rule.allow) {
+			allowed = rule.allow
+			length = len(rule.pattern)
+		}
+	}
+
+	return allowed
+}
@@ -0,0 +1,73 @@
+package crawler
+
+import "testing"
+
+// TestRobots checks the rule selection and matching of robots.txt.
+func TestRobots(t *testing.T) {
+	data := `# comment
+User-agent: Googlebot
+Disallow: /
+
+User-agent: *
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow:
+`
+
+	testCases := []struct {
+		userAgent string
+		path      string
+		expected  bool
+	}{
+		{userAgent: "lighthouse-keeper", path: "/", expected: true},
+		{userAgent: "lighthouse-keeper", path: "/private/page", expected: false},
+		{userAgent: "lighthouse-keeper", path: "/private/public/page", expected: true},
+		{userAgent: "lighthouse-keeper", path: "/docs/manual.pdf", expected: false},
+		{userAgent: "lighthouse-keeper", path: "/docs/manual.pdf?download=1", expected: true},
+		{userAgent: "Googlebot/2.1", path: "/about", expected: false},
+	}
+
+	for _, tc := range testCases {
+		r := parseRobots(data, tc.userAgent)
+		if allowed := r.allowed(tc.path); allowed != tc.expected {
+			t.Errorf("%s %s: expected allowed %t, got %t", tc.userAgent, tc.path, tc.expected, allowed)
+		}
+	}
+}
+
+// TestRobotsGroups checks which group applies to the crawler.
+func TestRobotsGroups(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		path     string
+		expected bool
+	}{
+		{
+			name:     "specific group allowing everything",
+			data:     "User-agent: *\nDisallow: /\n\nUser-agent: lighthouse-keeper\nDisallow:\n",
+			path:     "/about",
+			expected: true,
+		},
+		{
+			name:     "empty user agent",
+			data:     "User-agent:\nDisallow: /\n\nUser-agent: *\nDisallow: /private/\n",
+			path:     "/about",
+			expected: true,
+		},
+		{
+			name:     "empty user agent in a group",
+			data:     "User-agent: *\nUser-agent:\nDisallow: /\n",
+			path:     "/about",
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		r := parseRobots(tc.data, "lighthouse-keeper")
+		if allowed := r.allowed(tc.path); allowed != tc.expected {
+			t.Errorf("%s: expected allowed %t, got %t", tc.name, tc.expected, allowed)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package docker manages containers and networks with a Docker compatible
+// command line client, to provide the site under test.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Config holds the settings to create a Client.
+type Config struct {
+	// Binary is the command line client, like "docker" or "podman".
+	// Defaults to "docker".
+	Binary string
+}
+
+// Client executes the container command line client.
+type Client struct {
+	binary string
+}
+
+// New returns a Client.
+func New(config Config) (*Client, error) {
+	if config.Binary == "" {
+		config.Binary = "docker"
+	}
+
+	_, err := exec.LookPath(config.Binary)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "could not find %q in PATH", config.Binary)
+	}
+
+	return &Client{binary: config.Binary}, nil
+}
+
+// ContainerConfig describes a container to run in the background.
+type ContainerConfig struct {
+	// Name is the container name.
+	Name string
+	// Image is the image to run.
+	Image string
+	// Network is the network to connect the container to.
+	Network string
+	// Aliases are additional host names of the container in Network.
+	Aliases []string
+	// Args are passed to the container as its command.
+	Args []string
+}
+
+// namePrefix starts the names of everything lighthouse-keeper creates.
+const namePrefix = "lighthouse-keeper-"
+
+// RandomName returns a unique name, usable for containers, networks and
+// compose projects.
+func RandomName() (string, error) {
+	b := make([]byte, 6)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return namePrefix + hex.EncodeToString(b), nil
+}
+
+// CreateNetwork creates a user-defined bridge network.
+func (c *Client) CreateNetwork(ctx context.Context, name string) error {
+	_, err := c.command(ctx, "network", "create", name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// RemoveNetwork removes a network.
+func (c *Client) RemoveNetwork(ctx context.Context, name string) error {
+	_, err := c.command(ctx, "network", "rm", name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// RunDetached starts a container in the background.
+func (c *Client) RunDetached(ctx context.Context, config ContainerConfig) error {
+	args := []string{"run", "--detach", fmt.Sprintf("--name=%s", config.Name)}
+	if config.Network != "" {
+		args = append(args, fmt.Sprintf("--network=%s", config.Network))
+	}
+	for _, a := range config.Aliases {
+		args = append(args, fmt.Sprintf("--network-alias=%s", a))
+	}
+	args = append(args, config.Image)
+	args = append(args, config.Args...)
+
+	_, err := c.command(ctx, args...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// BridgeGateway returns the IPv4 address of the host in the default bridge
+// network, which the special host-gateway address resolves to in containers
+// on Linux.
+func (c *Client) BridgeGateway(ctx context.Context) (string, error) {
+	network, format := "bridge", "{{range .IPAM.Config}}{{.Gateway}} {{end}}"
+	if c.binary == "podman" {
+		network, format = "podman", "{{range .Subnets}}{{.Gateway}} {{end}}"
+	}
+
+	out, err := c.command(ctx, "network", "inspect", "--format", format, network)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	gateway := parseGateway(out)
+	if gateway == "" {
+		return "", microerror.Maskf(commandFailedError, "found no IPv4 gateway of network %s", network)
+	}
+
+	return gateway, nil
+}
+
+// parseGateway returns the first IPv4 address of the space separated
+// gateways.
+func parseGateway(out string) string {
+	for _, field := range strings.Fields(out) {
+		ip := net.ParseIP(field)
+		if ip != nil && ip.To4() != nil {
+			return ip.String()
+		}
+	}
+
+	return ""
+}
+
+// Logs returns the combined output of a container.
+func (c *Client) Logs(ctx context.Context, name string) (string, error) {
+	command := exec.CommandContext(ctx, c.binary, "logs", name)
+	out, err := command.CombinedOutput()
+	if err != nil {
+		return "", microerror.Maskf(commandFailedError, "%s logs %s: %s", c.binary, name, strings.TrimSpace(string(out)))
+	}
+
+	return string(out), nil
+}
+
+// RemoveContainer stops and removes a container.
+func (c *Client) RemoveContainer(ctx context.Context, name string) error {
+	_, err := c.command(ctx, "rm", "--force", name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// command executes the command line client and returns its standard output.
+func (c *Client) command(ctx context.Context, args ...string) (string, error) {
+	command := exec.CommandContext(ctx, c.binary, args...)
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	err := command.Run()
+	if err != nil {
+		subcommand := args
+		if len(subcommand) > 2 {
+			subcommand = subcommand[:2]
+		}
+		return "", microerror.Maskf(commandFailedError, "%s %s: %s", c.binary, strings.Join(subcommand, " "), strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,22 @@
+package docker
+
+import "testing"
+
+// TestParseGateway checks picking the IPv4 gateway of a network.
+func TestParseGateway(t *testing.T) {
+	testCases := []struct {
+		out      string
+		expected string
+	}{
+		{"172.17.0.1 \n", "172.17.0.1"},
+		{"fd00::1 10.88.0.1 \n", "10.88.0.1"},
+		{"\n", ""},
+	}
+
+	for _, tc := range testCases {
+		gateway := parseGateway(tc.out)
+		if gateway != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.out, tc.expected, gateway)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package docker
+
+import "github.com/giantswarm/microerror"
+
+// invalidConfigError is used when the client configuration is invalid
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfigError asserts invalidConfigError
+func IsInvalidConfigError(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+// commandFailedError is used when the command line client fails
+var commandFailedError = &microerror.Error{
+	Kind: "commandFailedError",
+}
+
+// IsCommandFailedError asserts commandFailedError
+func IsCommandFailedError(err error) bool {
+	return microerror.Cause(err) == commandFailedError
+}
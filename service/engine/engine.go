@@ -0,0 +1,780 @@
+// Package engine is a minimal client of the Docker Engine API, to run
+// containers without the docker command line client.
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// APIVersion is the Engine API version requests are made with. It is
+// supported by Docker 19.03 and newer.
+const APIVersion = "1.40"
+
+// DefaultHost is the address of the Docker daemon if neither the config nor
+// the DOCKER_HOST environment variable name one.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// dockerHubRegistry is the key of Docker Hub credentials in config.json.
+const dockerHubRegistry = "https://index.docker.io/v1/"
+
+// Config holds the settings to create a Client. Like the docker command
+// line client, the client is configured by the DOCKER_HOST,
+// DOCKER_TLS_VERIFY, DOCKER_CERT_PATH and DOCKER_CONFIG environment
+// variables.
+type Config struct {
+	// Host is the address of the daemon, either unix:///path/to/socket or
+	// tcp://host:port. Defaults to the DOCKER_HOST environment variable,
+	// or DefaultHost.
+	Host string
+	// TLSVerify connects to a tcp:// host with TLS, verifying the daemon
+	// and authenticating with the certificates in CertPath. Defaults to
+	// whether DOCKER_TLS_VERIFY is set.
+	TLSVerify bool
+	// CertPath is the directory holding ca.pem, cert.pem and key.pem.
+	// Defaults to the DOCKER_CERT_PATH environment variable, or
+	// ConfigDir.
+	CertPath string
+	// ConfigDir is the directory of the docker command line client's
+	// config.json, which holds the credentials for pulling images.
+	// Defaults to the DOCKER_CONFIG environment variable, or ~/.docker.
+	ConfigDir string
+}
+
+// Client talks to the Docker Engine API.
+type Client struct {
+	http    *http.Client
+	baseURL string
+	// auths are the registry credentials of config.json, base64 encoded
+	// user:password by registry host.
+	auths map[string]string
+}
+
+// New returns a Client for the configured daemon. Docker contexts and
+// ssh:// hosts are not supported, DOCKER_HOST has to name the daemon
+// instead.
+func New(config Config) (*Client, error) {
+	if config.ConfigDir == "" {
+		config.ConfigDir = os.Getenv("DOCKER_CONFIG")
+	}
+	if config.ConfigDir == "" && os.Getenv("HOME") != "" {
+		config.ConfigDir = filepath.Join(os.Getenv("HOME"), ".docker")
+	}
+	if config.CertPath == "" {
+		config.CertPath = os.Getenv("DOCKER_CERT_PATH")
+	}
+	if config.CertPath == "" {
+		config.CertPath = config.ConfigDir
+	}
+	if os.Getenv("DOCKER_TLS_VERIFY") != "" {
+		config.TLSVerify = true
+	}
+
+	cliConfig, err := readCLIConfig(config.ConfigDir)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	host := config.Host
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		context := os.Getenv("DOCKER_CONTEXT")
+		if context == "" {
+			context = cliConfig.CurrentContext
+		}
+		if context != "" && context != "default" {
+			return nil, microerror.Maskf(invalidConfigError, "docker context %q is not supported, set DOCKER_HOST to the address of its daemon", context)
+		}
+		host = DefaultHost
+	}
+
+	client := &Client{auths: map[string]string{}}
+	for registry, auth := range cliConfig.Auths {
+		if auth.Auth != "" {
+			client.auths[registryHost(registry)] = auth.Auth
+		}
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "docker host %q: %s", host, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		client.http = &http.Client{Transport: transport}
+		client.baseURL = "http://docker/v" + APIVersion
+		return client, nil
+	case "tcp", "http":
+		if !config.TLSVerify {
+			client.http = &http.Client{}
+			client.baseURL = fmt.Sprintf("http://%s/v%s", u.Host, APIVersion)
+			return client, nil
+		}
+
+		tlsConfig, err := loadTLSConfig(config.CertPath)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		client.http = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		client.baseURL = fmt.Sprintf("https://%s/v%s", u.Host, APIVersion)
+		return client, nil
+	}
+
+	return nil, microerror.Maskf(invalidConfigError, "docker host %q must start with unix:// or tcp://", host)
+}
+
+// cliConfig is the part of the docker command line client's config.json
+// used by Client. Credentials kept by credential helpers are not
+// supported.
+type cliConfig struct {
+	Auths map[string]struct {
+		Auth string `json:"auth"`
+	} `json:"auths"`
+	CurrentContext string `json:"currentContext"`
+}
+
+// readCLIConfig reads config.json in dir. A missing file is an empty config.
+func readCLIConfig(dir string) (cliConfig, error) {
+	var config cliConfig
+	if dir == "" {
+		return config, nil
+	}
+
+	path := filepath.Join(dir, "config.json")
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	} else if err != nil {
+		return config, microerror.Mask(err)
+	}
+
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return config, microerror.Maskf(invalidConfigError, "reading %s: %s", path, err)
+	}
+
+	return config, nil
+}
+
+// loadTLSConfig returns the TLS settings verifying the daemon with ca.pem
+// and authenticating with cert.pem and key.pem in dir.
+func loadTLSConfig(dir string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "loading the docker client certificate from %s: %s", dir, err)
+	}
+
+	ca, err := ioutil.ReadFile(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "loading the docker CA certificate from %s: %s", dir, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, microerror.Maskf(invalidConfigError, "no certificates found in %s", filepath.Join(dir, "ca.pem"))
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+	}, nil
+}
+
+// registryHost returns the host of a registry as named in config.json, with
+// or without scheme and path. Docker Hub has several names.
+func registryHost(registry string) string {
+	host := registry
+	if u, err := url.Parse(registry); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	host = strings.SplitN(host, "/", 2)[0]
+
+	switch host {
+	case "docker.io", "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+
+	return host
+}
+
+// imageRegistry returns the registry host of an image name. Names without
+// one refer to Docker Hub.
+func imageRegistry(name string) string {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return registryHost(parts[0])
+	}
+
+	return "docker.io"
+}
+
+// registryAuth returns the X-Registry-Auth header value for pulling the
+// image with the given name, or an empty string without credentials.
+func (c *Client) registryAuth(name string) (string, error) {
+	registry := imageRegistry(name)
+	auth, ok := c.auths[registry]
+	if !ok {
+		return "", nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(auth)
+	if err != nil {
+		return "", microerror.Maskf(invalidConfigError, "credentials of registry %s: %s", registry, err)
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", microerror.Maskf(invalidConfigError, "credentials of registry %s must have the form user:password", registry)
+	}
+
+	serverAddress := registry
+	if registry == "docker.io" {
+		serverAddress = dockerHubRegistry
+	}
+	data, err := json.Marshal(map[string]string{
+		"username":      parts[0],
+		"password":      parts[1],
+		"serveraddress": serverAddress,
+	})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+// Info describes the daemon.
+type Info struct {
+	// SecurityOptions contain name=rootless on rootless daemons.
+	SecurityOptions []string `json:"SecurityOptions"`
+}
+
+// Rootless tells whether the daemon runs rootless.
+func (i Info) Rootless() bool {
+	for _, o := range i.SecurityOptions {
+		if o == "name=rootless" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Image describes a local image.
+type Image struct {
+	ID          string   `json:"Id"`
+	RepoDigests []string `json:"RepoDigests"`
+}
+
+// Digest returns the registry digest of the image in the repository of
+// the given image reference. It is empty for images that were never pushed
+// to or pulled from that repository, like locally built or loaded ones.
+func (i Image) Digest(ref string) string {
+	name, _ := SplitReference(ref)
+	repository := normalizeRepository(name)
+
+	// RepoDigests entries have the form name@sha256:...
+	for _, d := range i.RepoDigests {
+		n, digest := SplitReference(d)
+		if normalizeRepository(n) == repository {
+			return digest
+		}
+	}
+
+	return ""
+}
+
+// normalizeRepository returns the short form of Docker Hub repositories,
+// e.g. "node" for "docker.io/library/node", as RepoDigests lists them.
+func normalizeRepository(name string) string {
+	for _, prefix := range []string{"docker.io/", "index.docker.io/"} {
+		if strings.HasPrefix(name, prefix) {
+			return strings.TrimPrefix(strings.TrimPrefix(name, prefix), "library/")
+		}
+	}
+
+	return strings.TrimPrefix(name, "library/")
+}
+
+// ContainerConfig describes a container to create.
+type ContainerConfig struct {
+	Image      string            `json:"Image"`
+	Cmd        []string          `json:"Cmd"`
+	WorkingDir string            `json:"WorkingDir,omitempty"`
+	User       string            `json:"User,omitempty"`
+	Env        []string          `json:"Env,omitempty"`
+	Labels     map[string]string `json:"Labels,omitempty"`
+	HostConfig HostConfig        `json:"HostConfig"`
+}
+
+// HostConfig holds the host specific settings of a container, like mounts
+// and networking.
+type HostConfig struct {
+	// Binds are host-src:container-dest[:ro] mounts.
+	Binds       []string `json:"Binds,omitempty"`
+	NetworkMode string   `json:"NetworkMode,omitempty"`
+	Links       []string `json:"Links,omitempty"`
+	ExtraHosts  []string `json:"ExtraHosts,omitempty"`
+	// NanoCpus is the CPU quota in billionths of a CPU.
+	NanoCpus   int64  `json:"NanoCpus,omitempty"`
+	Memory     int64  `json:"Memory,omitempty"`
+	CpusetCpus string `json:"CpusetCpus,omitempty"`
+	ShmSize    int64  `json:"ShmSize,omitempty"`
+}
+
+// Container is an entry of the container list.
+type Container struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	State  string            `json:"State"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// ContainerState is the state of a container.
+type ContainerState struct {
+	Status    string `json:"Status"`
+	ExitCode  int    `json:"ExitCode"`
+	OOMKilled bool   `json:"OOMKilled"`
+	Error     string `json:"Error"`
+}
+
+// Ping checks that the daemon is reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	err := c.do(ctx, "GET", "/_ping", nil, nil, nil)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Info returns information about the daemon.
+func (c *Client) Info(ctx context.Context) (Info, error) {
+	var info Info
+	err := c.do(ctx, "GET", "/info", nil, nil, &info)
+	if err != nil {
+		return Info{}, microerror.Mask(err)
+	}
+
+	return info, nil
+}
+
+// InspectImage returns the local image with the given reference.
+func (c *Client) InspectImage(ctx context.Context, ref string) (Image, error) {
+	var image Image
+	err := c.do(ctx, "GET", "/images/"+ref+"/json", nil, nil, &image)
+	if IsNotFound(err) {
+		return Image{}, microerror.Maskf(imageNotFoundError, "image %q is not available locally", ref)
+	} else if err != nil {
+		return Image{}, microerror.Mask(err)
+	}
+
+	return image, nil
+}
+
+// PullImage pulls the image with the given reference, with the credentials
+// of its registry in config.json if there are any. Progress messages are
+// written to out.
+func (c *Client) PullImage(ctx context.Context, ref string, out io.Writer) error {
+	name, tag := SplitReference(ref)
+	query := url.Values{"fromImage": {name}, "tag": {tag}}
+
+	header := http.Header{}
+	auth, err := c.registryAuth(name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if auth != "" {
+		header.Set("X-Registry-Auth", auth)
+	}
+
+	err = c.stream(ctx, "POST", "/images/create", query, header, nil, "", out)
+	if err != nil {
+		return pullError(ref, err)
+	}
+
+	return nil
+}
+
+// LoadImage loads images from a tarball as written by docker save. Progress
+// messages are written to out.
+func (c *Client) LoadImage(ctx context.Context, archive io.Reader, out io.Writer) error {
+	err := c.stream(ctx, "POST", "/images/load", url.Values{"quiet": {"1"}}, nil, archive, "application/x-tar", out)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// CreateContainer creates a container with the given name and returns its
+// ID.
+func (c *Client) CreateContainer(ctx context.Context, name string, config ContainerConfig) (string, error) {
+	body, err := json.Marshal(config)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err = c.do(ctx, "POST", "/containers/create", url.Values{"name": {name}}, bytes.NewReader(body), &created)
+	if IsNotFound(err) {
+		return "", microerror.Maskf(imageNotFoundError, "image %q is not available locally", config.Image)
+	} else if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return created.ID, nil
+}
+
+// StartContainer starts a created container.
+func (c *Client) StartContainer(ctx context.Context, id string) error {
+	err := c.do(ctx, "POST", "/containers/"+id+"/start", nil, nil, nil)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// WaitContainer waits for a container to stop and returns its exit code.
+func (c *Client) WaitContainer(ctx context.Context, id string) (int, error) {
+	var result struct {
+		StatusCode int `json:"StatusCode"`
+		Error      *struct {
+			Message string `json:"Message"`
+		} `json:"Error"`
+	}
+	err := c.do(ctx, "POST", "/containers/"+id+"/wait", nil, nil, &result)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+	if result.Error != nil && result.Error.Message != "" {
+		return 0, microerror.Maskf(apiError, "waiting for container %s: %s", id, result.Error.Message)
+	}
+
+	return result.StatusCode, nil
+}
+
+// Logs streams the output of a container without TTY to stdout and stderr
+// until the container stops.
+func (c *Client) Logs(ctx context.Context, id string, stdout, stderr io.Writer) error {
+	query := url.Values{"stdout": {"1"}, "stderr": {"1"}, "follow": {"1"}}
+	resp, err := c.request(ctx, "GET", "/containers/"+id+"/logs", query, nil, nil, "")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	err = demux(resp.Body, stdout, stderr)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// InspectContainer returns the state of a container.
+func (c *Client) InspectContainer(ctx context.Context, id string) (ContainerState, error) {
+	var container struct {
+		State ContainerState `json:"State"`
+	}
+	err := c.do(ctx, "GET", "/containers/"+id+"/json", nil, nil, &container)
+	if err != nil {
+		return ContainerState{}, microerror.Mask(err)
+	}
+
+	return container.State, nil
+}
+
+// RemoveContainer removes a container, stopping it if it is running.
+// Containers that don't exist anymore are ignored.
+func (c *Client) RemoveContainer(ctx context.Context, id string) error {
+	err := c.do(ctx, "DELETE", "/containers/"+id, url.Values{"force": {"1"}}, nil, nil)
+	if err != nil && !IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// ListContainers returns all containers, running or not, carrying the given
+// label, given as key or key=value.
+func (c *Client) ListContainers(ctx context.Context, label string) ([]Container, error) {
+	filters, err := json.Marshal(map[string][]string{"label": {label}})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var containers []Container
+	err = c.do(ctx, "GET", "/containers/json", url.Values{"all": {"1"}, "filters": {string(filters)}}, nil, &containers)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return containers, nil
+}
+
+// Run creates and starts a container, streams its output to stdout and
+// stderr until it stops and removes it. Containers killed for running out
+// of memory or exiting with a non-zero code return oomKilledError or
+// exitError. If ctx is done before the container stops, it is removed and
+// canceledError is returned, or removeError if removing it failed.
+func (c *Client) Run(ctx context.Context, name string, config ContainerConfig, stdout, stderr io.Writer) error {
+	id, err := c.CreateContainer(ctx, name, config)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	// the container must go even if ctx is done already
+	defer c.RemoveContainer(context.Background(), id)
+
+	err = c.StartContainer(ctx, id)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	logsDone := make(chan error, 1)
+	go func() {
+		logsDone <- c.Logs(ctx, id, stdout, stderr)
+	}()
+
+	exitCode, err := c.WaitContainer(ctx, id)
+	if ctx.Err() != nil {
+		err = c.RemoveContainer(context.Background(), id)
+		if err != nil {
+			return microerror.Maskf(removeError, "could not remove container %s: %s", name, err)
+		}
+		return microerror.Maskf(canceledError, "removed container %s", name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = <-logsDone
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	state, err := c.InspectContainer(ctx, id)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if state.OOMKilled {
+		return microerror.Maskf(oomKilledError, "container %s ran out of memory and was killed", name)
+	}
+	if exitCode != 0 {
+		return microerror.Maskf(exitError, "container %s exited with code %d", name, exitCode)
+	}
+
+	return nil
+}
+
+// SplitReference splits an image reference into the image name and its tag
+// or digest, defaulting to the latest tag.
+func SplitReference(ref string) (string, string) {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+
+	// a colon before the last slash separates a registry port
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:]
+	}
+
+	return ref, "latest"
+}
+
+// statusError is returned by request for responses with an unexpected
+// status. IsAPIError asserts it as well.
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("docker engine responded with status %d: %s", e.status, e.message)
+}
+
+// IsNotFound tells whether the daemon responded with 404 Not Found.
+func IsNotFound(err error) bool {
+	s, ok := microerror.Cause(err).(*statusError)
+	return ok && s.status == http.StatusNotFound
+}
+
+// pullError classifies errors of pulling the given image.
+func pullError(ref string, err error) error {
+	message := err.Error()
+	if s, ok := microerror.Cause(err).(*statusError); ok {
+		message = s.message
+	}
+
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "denied"), strings.Contains(lower, "unauthorized"):
+		return microerror.Maskf(pullDeniedError, "pulling image %q: %s", ref, message)
+	case IsNotFound(err), strings.Contains(lower, "not found"), strings.Contains(lower, "manifest unknown"):
+		return microerror.Maskf(imageNotFoundError, "pulling image %q: %s", ref, message)
+	}
+
+	return microerror.Maskf(apiError, "pulling image %q: %s", ref, message)
+}
+
+// request sends a request to the Engine API with the given extra headers and
+// returns the response if its status is 2xx.
+func (c *Client) request(ctx context.Context, method, path string, query url.Values, header http.Header, body io.Reader, contentType string) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	req = req.WithContext(ctx)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, microerror.Mask(ctx.Err())
+		}
+		return nil, microerror.Maskf(apiError, "could not reach the docker engine: %s", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		var message struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &message) != nil || message.Message == "" {
+			message.Message = strings.TrimSpace(string(data))
+		}
+		return nil, microerror.Mask(&statusError{status: resp.StatusCode, message: message.Message})
+	}
+
+	return resp, nil
+}
+
+// do sends a request and decodes the JSON response into result, if given.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, result interface{}) error {
+	resp, err := c.request(ctx, method, path, query, nil, body, "application/json")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	if result == nil {
+		_, err = io.Copy(ioutil.Discard, resp.Body)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return microerror.Maskf(apiError, "decoding the response of %s %s: %s", method, path, err)
+	}
+
+	return nil
+}
+
+// stream sends a request answered with a stream of JSON progress messages,
+// as for pulling and loading images, and writes their status to out. Errors
+// reported in the stream are returned.
+func (c *Client) stream(ctx context.Context, method, path string, query url.Values, header http.Header, body io.Reader, contentType string, out io.Writer) error {
+	resp, err := c.request(ctx, method, path, query, header, body, contentType)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+			Stream string `json:"stream"`
+			Error  string `json:"error"`
+		}
+		err = decoder.Decode(&message)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return microerror.Maskf(apiError, "decoding the response of %s %s: %s", method, path, err)
+		}
+
+		if message.Error != "" {
+			return microerror.Mask(&statusError{status: resp.StatusCode, message: message.Error})
+		}
+		// progress of single layers is too noisy to print
+		if message.Status != "" && message.ID == "" {
+			fmt.Fprintln(out, message.Status)
+		}
+		if s := strings.TrimSpace(message.Stream); s != "" {
+			fmt.Fprintln(out, s)
+		}
+	}
+}
+
+// demux splits the multiplexed output stream of a container without TTY.
+// Each frame has an 8 byte header: the stream type, three zero bytes and the
+// big endian frame size.
+func demux(r io.Reader, stdout, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		_, err := io.ReadFull(r, header)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return microerror.Maskf(apiError, "reading container output: %s", err)
+		}
+
+		var w io.Writer
+		switch header[0] {
+		case 0, 1:
+			w = stdout
+		case 2:
+			w = stderr
+		default:
+			return microerror.Maskf(apiError, "reading container output: unknown stream %d", header[0])
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		_, err = io.CopyN(w, r, size)
+		if err != nil {
+			return microerror.Maskf(apiError, "reading container output: %s", err)
+		}
+	}
+}
@@ -0,0 +1,564 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeContainer is a container of the fake engine. Its command decides what
+// it does: "echo" prints its arguments, "fail" exits with code 3, "oom" is
+// killed for running out of memory and "sleep" runs until it is removed.
+type fakeContainer struct {
+	name   string
+	config ContainerConfig
+	state  ContainerState
+	stdout string
+	stderr string
+	done   chan struct{}
+}
+
+// fakeEngine serves the part of the Engine API used by Client.
+type fakeEngine struct {
+	mu         sync.Mutex
+	images     map[string]Image
+	registry   map[string]Image
+	containers map[string]*fakeContainer
+	nextID     int
+	// pullAuth is the X-Registry-Auth header of the last pull.
+	pullAuth string
+	// removeFails makes removing containers fail.
+	removeFails bool
+}
+
+func newFakeEngine() *fakeEngine {
+	return &fakeEngine{
+		images:     map[string]Image{},
+		registry:   map[string]Image{},
+		containers: map[string]*fakeContainer{},
+	}
+}
+
+// startFakeEngine serves a fake engine on a unix socket and returns a client
+// for it.
+func startFakeEngine(t *testing.T, engine *fakeEngine) (*Client, func()) {
+	dir, err := ioutil.TempDir("", "engine-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(engine)
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+
+	client, err := New(Config{Host: "unix://" + socket})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := "/v" + APIVersion
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		apiErrorResponse(w, http.StatusBadRequest, "unsupported API version")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch {
+	case path == "/_ping":
+		w.Write([]byte("OK"))
+	case path == "/info":
+		json.NewEncoder(w).Encode(Info{SecurityOptions: []string{"name=seccomp,profile=default", "name=rootless"}})
+	case r.Method == "GET" && strings.HasPrefix(path, "/images/") && strings.HasSuffix(path, "/json"):
+		ref := strings.TrimSuffix(strings.TrimPrefix(path, "/images/"), "/json")
+		image, ok := e.images[ref]
+		if !ok {
+			apiErrorResponse(w, http.StatusNotFound, "no such image: "+ref)
+			return
+		}
+		json.NewEncoder(w).Encode(image)
+	case r.Method == "POST" && path == "/images/create":
+		ref := r.URL.Query().Get("fromImage") + ":" + r.URL.Query().Get("tag")
+		e.pullAuth = r.Header.Get("X-Registry-Auth")
+		if strings.Contains(ref, "private") {
+			apiErrorResponse(w, http.StatusNotFound, "pull access denied for "+ref+", repository does not exist or may require 'docker login'")
+			return
+		}
+		fmt.Fprintf(w, "{\"status\":\"Pulling from %s\"}\n", ref)
+		image, ok := e.registry[ref]
+		if !ok {
+			fmt.Fprintf(w, "{\"error\":\"manifest for %s not found: manifest unknown\"}\n", ref)
+			return
+		}
+		fmt.Fprintf(w, "{\"status\":\"Downloading\",\"id\":\"abc\"}\n")
+		fmt.Fprintf(w, "{\"status\":\"Status: Downloaded newer image for %s\"}\n", ref)
+		e.images[ref] = image
+	case r.Method == "POST" && path == "/images/load":
+		data, _ := ioutil.ReadAll(r.Body)
+		ref := strings.TrimSpace(string(data))
+		e.images[ref] = Image{ID: "sha256:loaded"}
+		fmt.Fprintf(w, "{\"stream\":\"Loaded image: %s\\n\"}\n", ref)
+	case r.Method == "POST" && path == "/containers/create":
+		var config ContainerConfig
+		err := json.NewDecoder(r.Body).Decode(&config)
+		if err != nil {
+			apiErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, ok := e.images[config.Image]; !ok {
+			apiErrorResponse(w, http.StatusNotFound, "no such image: "+config.Image)
+			return
+		}
+		e.nextID++
+		id := fmt.Sprintf("container%d", e.nextID)
+		e.containers[id] = &fakeContainer{
+			name:   r.URL.Query().Get("name"),
+			config: config,
+			state:  ContainerState{Status: "created"},
+			done:   make(chan struct{}),
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "{\"Id\":%q}", id)
+	case r.Method == "GET" && path == "/containers/json":
+		containers := []Container{}
+		var filters map[string][]string
+		json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+		for id, c := range e.containers {
+			for _, label := range filters["label"] {
+				if _, ok := c.config.Labels[label]; ok {
+					containers = append(containers, Container{ID: id, Names: []string{"/" + c.name}, State: c.state.Status, Labels: c.config.Labels})
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(containers)
+	case strings.HasPrefix(path, "/containers/"):
+		parts := strings.SplitN(strings.TrimPrefix(path, "/containers/"), "/", 2)
+		c, ok := e.containers[parts[0]]
+		if !ok {
+			apiErrorResponse(w, http.StatusNotFound, "no such container: "+parts[0])
+			return
+		}
+		action := ""
+		if len(parts) > 1 {
+			action = parts[1]
+		}
+		e.serveContainer(w, r, parts[0], c, action)
+	default:
+		apiErrorResponse(w, http.StatusNotFound, "page not found")
+	}
+}
+
+func (e *fakeEngine) serveContainer(w http.ResponseWriter, r *http.Request, id string, c *fakeContainer, action string) {
+	switch {
+	case r.Method == "POST" && action == "start":
+		c.state.Status = "running"
+		switch c.config.Cmd[0] {
+		case "echo":
+			c.stdout = strings.Join(c.config.Cmd[1:], " ") + "\n"
+		case "fail":
+			c.stderr = "boom\n"
+			c.state.ExitCode = 3
+		case "oom":
+			c.state.ExitCode = 137
+			c.state.OOMKilled = true
+		case "sleep":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		c.state.Status = "exited"
+		close(c.done)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "POST" && action == "wait":
+		e.mu.Unlock()
+		select {
+		case <-c.done:
+		case <-r.Context().Done():
+		}
+		e.mu.Lock()
+		fmt.Fprintf(w, "{\"StatusCode\":%d}", c.state.ExitCode)
+	case r.Method == "GET" && action == "logs":
+		e.mu.Unlock()
+		select {
+		case <-c.done:
+		case <-r.Context().Done():
+		}
+		e.mu.Lock()
+		writeFrame(w, 1, c.stdout)
+		writeFrame(w, 2, c.stderr)
+	case r.Method == "GET" && action == "json":
+		json.NewEncoder(w).Encode(map[string]interface{}{"State": c.state})
+	case r.Method == "DELETE" && action == "":
+		if e.removeFails {
+			apiErrorResponse(w, http.StatusInternalServerError, "removal of container is already in progress")
+			return
+		}
+		if c.state.Status == "running" {
+			c.state.Status = "exited"
+			c.state.ExitCode = 137
+			close(c.done)
+		}
+		delete(e.containers, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		apiErrorResponse(w, http.StatusNotFound, "page not found")
+	}
+}
+
+func (e *fakeEngine) containerCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.containers)
+}
+
+func apiErrorResponse(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// writeFrame writes output in the multiplexed format of containers without
+// TTY.
+func writeFrame(w http.ResponseWriter, stream byte, s string) {
+	if s == "" {
+		return
+	}
+	header := []byte{stream, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header[4:], uint32(len(s)))
+	w.Write(header)
+	w.Write([]byte(s))
+}
+
+// TestRun checks the output and errors of containers, and that they are
+// removed after running.
+func TestRun(t *testing.T) {
+	engine := newFakeEngine()
+	engine.images["lighthouse:latest"] = Image{ID: "sha256:abc"}
+	client, stop := startFakeEngine(t, engine)
+	defer stop()
+
+	testCases := []struct {
+		cmd            []string
+		expectedStdout string
+		expectedStderr string
+		check          func(error) bool
+	}{
+		{[]string{"echo", "hello", "world"}, "hello world\n", "", func(err error) bool { return err == nil }},
+		{[]string{"fail"}, "", "boom\n", IsExitError},
+		{[]string{"oom"}, "", "", IsOOMKilledError},
+	}
+
+	for _, tc := range testCases {
+		var stdout, stderr bytes.Buffer
+		config := ContainerConfig{
+			Image:  "lighthouse:latest",
+			Cmd:    tc.cmd,
+			Labels: map[string]string{"test": "true"},
+		}
+		err := client.Run(context.Background(), "test", config, &stdout, &stderr)
+		if !tc.check(err) {
+			t.Errorf("%v: unexpected error %v", tc.cmd, err)
+		}
+		if stdout.String() != tc.expectedStdout || stderr.String() != tc.expectedStderr {
+			t.Errorf("%v: expected output %q and %q, got %q and %q", tc.cmd, tc.expectedStdout, tc.expectedStderr, stdout.String(), stderr.String())
+		}
+		if n := engine.containerCount(); n != 0 {
+			t.Errorf("%v: expected the container to be removed, %d left", tc.cmd, n)
+		}
+	}
+
+	err := client.Run(context.Background(), "test", ContainerConfig{Image: "missing:latest", Cmd: []string{"echo"}}, ioutil.Discard, ioutil.Discard)
+	if !IsImageNotFoundError(err) {
+		t.Errorf("expected image not found error, got %v", err)
+	}
+}
+
+// TestRunCancel checks that a running container is removed once the context
+// is done, and that failing to remove it is reported.
+func TestRunCancel(t *testing.T) {
+	testCases := []struct {
+		name              string
+		removeFails       bool
+		expectedError     func(error) bool
+		expectedContainer int
+	}{
+		{name: "removed", expectedError: IsCanceledError, expectedContainer: 0},
+		{name: "removal fails", removeFails: true, expectedError: IsRemoveError, expectedContainer: 1},
+	}
+
+	for _, tc := range testCases {
+		engine := newFakeEngine()
+		engine.images["lighthouse:latest"] = Image{ID: "sha256:abc"}
+		engine.removeFails = tc.removeFails
+		client, stop := startFakeEngine(t, engine)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+
+		err := client.Run(ctx, "test", ContainerConfig{Image: "lighthouse:latest", Cmd: []string{"sleep"}}, ioutil.Discard, ioutil.Discard)
+		if !tc.expectedError(err) || ctx.Err() == nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if n := engine.containerCount(); n != tc.expectedContainer {
+			t.Errorf("%s: expected %d containers left, got %d", tc.name, tc.expectedContainer, n)
+		}
+
+		cancel()
+		stop()
+	}
+}
+
+// TestImages checks inspecting, pulling and loading images.
+func TestImages(t *testing.T) {
+	engine := newFakeEngine()
+	engine.registry["example.com:5000/lighthouse:4.0.0"] = Image{ID: "sha256:abc", RepoDigests: []string{"example.com:5000/lighthouse@sha256:def"}}
+	client, stop := startFakeEngine(t, engine)
+	defer stop()
+	client.auths = map[string]string{"example.com:5000": base64.StdEncoding.EncodeToString([]byte("user:secret"))}
+	ctx := context.Background()
+
+	_, err := client.InspectImage(ctx, "example.com:5000/lighthouse:4.0.0")
+	if !IsImageNotFoundError(err) {
+		t.Errorf("expected image not found error before pulling, got %v", err)
+	}
+
+	var out bytes.Buffer
+	err = client.PullImage(ctx, "example.com:5000/lighthouse:4.0.0", &out)
+	if err != nil {
+		t.Fatalf("unexpected error pulling: %s", err)
+	}
+	if strings.Contains(out.String(), "Downloading") || !strings.Contains(out.String(), "Downloaded newer image") {
+		t.Errorf("expected only the overall pull progress, got %q", out.String())
+	}
+	auth, err := base64.URLEncoding.DecodeString(engine.pullAuth)
+	if err != nil || string(auth) != `{"password":"secret","serveraddress":"example.com:5000","username":"user"}` {
+		t.Errorf("expected the registry credentials to be sent, got %q", auth)
+	}
+
+	image, err := client.InspectImage(ctx, "example.com:5000/lighthouse:4.0.0")
+	if err != nil {
+		t.Fatalf("unexpected error inspecting: %s", err)
+	}
+	if image.Digest("example.com:5000/lighthouse:4.0.0") != "sha256:def" {
+		t.Errorf("expected digest sha256:def, got %q", image.Digest("example.com:5000/lighthouse:4.0.0"))
+	}
+
+	err = client.PullImage(ctx, "lighthouse:unknown", ioutil.Discard)
+	if !IsImageNotFoundError(err) {
+		t.Errorf("expected image not found error, got %v", err)
+	}
+	if engine.pullAuth != "" {
+		t.Errorf("expected no credentials for another registry, got %q", engine.pullAuth)
+	}
+
+	err = client.PullImage(ctx, "example.com/private/lighthouse", ioutil.Discard)
+	if !IsPullDeniedError(err) {
+		t.Errorf("expected pull denied error, got %v", err)
+	}
+
+	err = client.LoadImage(ctx, strings.NewReader("archived:1.0"), ioutil.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error loading: %s", err)
+	}
+	image, err = client.InspectImage(ctx, "archived:1.0")
+	if err != nil {
+		t.Fatalf("unexpected error inspecting the loaded image: %s", err)
+	}
+	if image.Digest("archived:1.0") != "" {
+		t.Errorf("expected no digest of a loaded image, got %q", image.Digest("archived:1.0"))
+	}
+}
+
+// TestListContainers checks finding containers by label.
+func TestListContainers(t *testing.T) {
+	engine := newFakeEngine()
+	engine.images["lighthouse:latest"] = Image{ID: "sha256:abc"}
+	client, stop := startFakeEngine(t, engine)
+	defer stop()
+	ctx := context.Background()
+
+	_, err := client.CreateContainer(ctx, "ours", ContainerConfig{Image: "lighthouse:latest", Cmd: []string{"echo"}, Labels: map[string]string{"ours": "true"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.CreateContainer(ctx, "other", ContainerConfig{Image: "lighthouse:latest", Cmd: []string{"echo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	containers, err := client.ListContainers(ctx, "ours")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 1 || containers[0].Names[0] != "/ours" {
+		t.Fatalf("expected only the labeled container, got %+v", containers)
+	}
+
+	err = client.RemoveContainer(ctx, containers[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.RemoveContainer(ctx, containers[0].ID)
+	if err != nil {
+		t.Errorf("expected removing a removed container to succeed, got %s", err)
+	}
+
+	info, err := client.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Rootless() {
+		t.Errorf("expected a rootless daemon")
+	}
+}
+
+// TestSplitReference checks splitting image references into name and tag.
+func TestSplitReference(t *testing.T) {
+	testCases := []struct {
+		ref          string
+		expectedName string
+		expectedTag  string
+	}{
+		{"lighthouse", "lighthouse", "latest"},
+		{"quay.io/giantswarm/lighthouse:4.0.0", "quay.io/giantswarm/lighthouse", "4.0.0"},
+		{"localhost:5000/lighthouse", "localhost:5000/lighthouse", "latest"},
+		{"localhost:5000/lighthouse:1.2", "localhost:5000/lighthouse", "1.2"},
+		{"lighthouse@sha256:abc", "lighthouse", "sha256:abc"},
+	}
+
+	for _, tc := range testCases {
+		name, tag := SplitReference(tc.ref)
+		if name != tc.expectedName || tag != tc.expectedTag {
+			t.Errorf("%q: expected %q and %q, got %q and %q", tc.ref, tc.expectedName, tc.expectedTag, name, tag)
+		}
+	}
+}
+
+// TestImageDigest checks picking the digest of the image's repository.
+func TestImageDigest(t *testing.T) {
+	image := Image{
+		ID: "sha256:id",
+		RepoDigests: []string{
+			"mirror.example.com/giantswarm/lighthouse@sha256:mirror",
+			"quay.io/giantswarm/lighthouse@sha256:quay",
+			"node@sha256:node",
+		},
+	}
+
+	testCases := []struct {
+		ref      string
+		expected string
+	}{
+		{"quay.io/giantswarm/lighthouse:latest", "sha256:quay"},
+		{"mirror.example.com/giantswarm/lighthouse", "sha256:mirror"},
+		{"docker.io/library/node:12", "sha256:node"},
+		{"library/node", "sha256:node"},
+		{"example.com/giantswarm/lighthouse", ""},
+	}
+
+	for _, tc := range testCases {
+		digest := image.Digest(tc.ref)
+		if digest != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.ref, tc.expected, digest)
+		}
+	}
+}
+
+// TestNew checks the accepted daemon addresses and the settings read from
+// the docker config directory.
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name       string
+		host       string
+		tlsVerify  bool
+		configJSON string
+		valid      bool
+	}{
+		{name: "unix socket", host: "unix:///var/run/docker.sock", valid: true},
+		{name: "tcp", host: "tcp://127.0.0.1:2375", valid: true},
+		{name: "ssh", host: "ssh://user@host", valid: false},
+		{name: "tls without certificates", host: "tcp://127.0.0.1:2376", tlsVerify: true, valid: false},
+		{name: "credentials", configJSON: `{"auths": {"https://index.docker.io/v1/": {"auth": "dXNlcjpzZWNyZXQ="}}}`, valid: true},
+		{name: "default context", configJSON: `{"currentContext": "default"}`, valid: true},
+		{name: "other context", configJSON: `{"currentContext": "remote"}`, valid: false},
+		{name: "context with host", host: "unix:///var/run/docker.sock", configJSON: `{"currentContext": "remote"}`, valid: true},
+		{name: "broken config", configJSON: `{"auths": [}`, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "engine-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			if tc.configJSON != "" {
+				err = ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(tc.configJSON), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err = New(Config{Host: tc.host, TLSVerify: tc.tlsVerify, ConfigDir: dir})
+			if tc.valid && err != nil {
+				t.Errorf("unexpected error %s", err)
+			} else if !tc.valid && !IsInvalidConfigError(err) {
+				t.Errorf("expected invalid config error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestImageRegistry checks finding the credentials of an image's registry.
+func TestImageRegistry(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"lighthouse", "docker.io"},
+		{"giantswarm/lighthouse", "docker.io"},
+		{"quay.io/giantswarm/lighthouse", "quay.io"},
+		{"localhost:5000/lighthouse", "localhost:5000"},
+		{"localhost/lighthouse", "localhost"},
+	}
+
+	for _, tc := range testCases {
+		if registry := imageRegistry(tc.name); registry != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.name, tc.expected, registry)
+		}
+	}
+
+	for _, registry := range []string{"https://index.docker.io/v1/", "docker.io", "https://quay.io", "quay.io"} {
+		host := registryHost(registry)
+		if host != "docker.io" && host != "quay.io" {
+			t.Errorf("%q: unexpected host %q", registry, host)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package engine
+
+import "github.com/giantswarm/microerror"
+
+// invalidConfigError is used when the client configuration is invalid
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfigError asserts invalidConfigError
+func IsInvalidConfigError(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+// apiError is used when the Engine API responds with an unexpected status
+var apiError = &microerror.Error{
+	Kind: "apiError",
+}
+
+// IsAPIError asserts apiError and errors for responses with an unexpected
+// status
+func IsAPIError(err error) bool {
+	cause := microerror.Cause(err)
+	_, ok := cause.(*statusError)
+	return cause == apiError || ok
+}
+
+// imageNotFoundError is used when an image is neither available locally nor
+// in its registry
+var imageNotFoundError = &microerror.Error{
+	Kind: "imageNotFoundError",
+}
+
+// IsImageNotFoundError asserts imageNotFoundError
+func IsImageNotFoundError(err error) bool {
+	return microerror.Cause(err) == imageNotFoundError
+}
+
+// pullDeniedError is used when the registry denies pulling an image
+var pullDeniedError = &microerror.Error{
+	Kind: "pullDeniedError",
+}
+
+// IsPullDeniedError asserts pullDeniedError
+func IsPullDeniedError(err error) bool {
+	return microerror.Cause(err) == pullDeniedError
+}
+
+// oomKilledError is used when a container is killed for running out of
+// memory
+var oomKilledError = &microerror.Error{
+	Kind: "oomKilledError",
+}
+
+// IsOOMKilledError asserts oomKilledError
+func IsOOMKilledError(err error) bool {
+	return microerror.Cause(err) == oomKilledError
+}
+
+// exitError is used when a container exits with a non-zero exit code
+var exitError = &microerror.Error{
+	Kind: "exitError",
+}
+
+// IsExitError asserts exitError
+func IsExitError(err error) bool {
+	return microerror.Cause(err) == exitError
+}
+
+// canceledError is used when a container was removed because the context
+// was done before it stopped
+var canceledError = &microerror.Error{
+	Kind: "canceledError",
+}
+
+// IsCanceledError asserts canceledError
+func IsCanceledError(err error) bool {
+	return microerror.Cause(err) == canceledError
+}
+
+// removeError is used when a container could not be removed after the
+// context was done
+var removeError = &microerror.Error{
+	Kind: "removeError",
+}
+
+// IsRemoveError asserts removeError
+func IsRemoveError(err error) bool {
+	return microerror.Cause(err) == removeError
+}
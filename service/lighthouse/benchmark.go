@@ -0,0 +1,90 @@
+package lighthouse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/giantswarm/microerror"
+)
+
+// BenchmarkRange is the range of lighthouse's benchmark index, a measure of
+// the CPU speed available to Chrome, in which scores are trusted.
+type BenchmarkRange struct {
+	// Min is the lowest accepted index. Lower values mean the host is too
+	// slow or too busy. Zero means no minimum.
+	Min float64
+	// Max is the highest accepted index. Zero means no maximum.
+	Max float64
+	// Fail makes runs outside the range fail, so they are retried like
+	// broken runs, instead of printing a warning.
+	Fail bool
+}
+
+// Validate checks that the range is valid.
+func (b BenchmarkRange) Validate() error {
+	if b.Min < 0 || b.Max < 0 {
+		return microerror.Maskf(invalidConfigError, "benchmark index bounds must not be negative")
+	}
+	if b.Max > 0 && b.Min > b.Max {
+		return microerror.Maskf(invalidConfigError, "minimum benchmark index %g is above the maximum %g", b.Min, b.Max)
+	}
+
+	return nil
+}
+
+// readBenchmarkIndex returns environment.benchmarkIndex of the report at the
+// given path, or zero if lighthouse didn't record one.
+func readBenchmarkIndex(path string) (float64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	var report struct {
+		Environment struct {
+			BenchmarkIndex float64 `json:"benchmarkIndex"`
+		} `json:"environment"`
+	}
+	err = json.Unmarshal(data, &report)
+	if err != nil {
+		return 0, microerror.Maskf(brokenReportError, "parsing report: %s", err)
+	}
+
+	return report.Environment.BenchmarkIndex, nil
+}
+
+// checkBenchmark checks the benchmark index of the report at the given path
+// against the configured range. Indexes outside of it are printed as a
+// warning, or returned as benchmarkError if the range is strict.
+func checkBenchmark(config Config, path string) error {
+	r := config.BenchmarkRange
+	if r.Min == 0 && r.Max == 0 {
+		return nil
+	}
+
+	index, err := readBenchmarkIndex(path)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var problem string
+	switch {
+	case index == 0:
+		fmt.Fprintf(config.Output, "Warning: the report has no benchmark index to check\n")
+		return nil
+	case r.Min > 0 && index < r.Min:
+		problem = fmt.Sprintf("benchmark index %.0f is below the minimum %g, the host is too slow or too busy for reliable scores", index, r.Min)
+	case r.Max > 0 && index > r.Max:
+		problem = fmt.Sprintf("benchmark index %.0f is above the maximum %g, scores are not comparable to those of slower hosts", index, r.Max)
+	default:
+		return nil
+	}
+
+	if r.Fail {
+		return microerror.Maskf(benchmarkError, "%s", problem)
+	}
+	fmt.Fprintf(config.Output, "Warning: %s\n", problem)
+
+	return nil
+}
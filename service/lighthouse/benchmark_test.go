@@ -0,0 +1,99 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAuditURLBenchmark checks that the benchmark index is recorded and
+// checked against the configured range. Fixture 001 has index 534, fixture
+// 002 has index 540.
+func TestAuditURLBenchmark(t *testing.T) {
+	warnRunner := newFakeRunner(t)
+	failRunner := newFakeRunner(t)
+
+	defer inTempDir(t)()
+
+	output := &bytes.Buffer{}
+	config := Config{
+		URL:            "https://example.com/",
+		Name:           "warn",
+		Runner:         warnRunner,
+		Output:         output,
+		BenchmarkRange: BenchmarkRange{Min: 600},
+	}
+
+	path, err := AuditURL(context.Background(), config)
+	if err != nil {
+		t.Fatalf("expected a warning only, got %s", err)
+	}
+	if !strings.Contains(output.String(), "Warning: benchmark index 534 is below the minimum 600") {
+		t.Errorf("expected a warning, got %q", output.String())
+	}
+	meta, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.BenchmarkIndex != 534 {
+		t.Errorf("expected benchmark index 534 in the metadata, got %g", meta.BenchmarkIndex)
+	}
+
+	// the first run is below the minimum, its retry is within the range
+	config.Name = "fail"
+	config.Runner = failRunner
+	config.BenchmarkRange = BenchmarkRange{Min: 536, Max: 600, Fail: true}
+	config.Retries = 1
+	config.RetryBackoff = time.Millisecond
+	path, err = AuditURL(context.Background(), config)
+	if err != nil {
+		t.Fatalf("expected the retry to succeed, got %s", err)
+	}
+	meta, err = ReadMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.BenchmarkIndex != 540 || meta.Attempts != 2 {
+		t.Errorf("expected benchmark index 540 after 2 attempts, got %g after %d", meta.BenchmarkIndex, meta.Attempts)
+	}
+
+	config.Name = "slow"
+	config.BenchmarkRange = BenchmarkRange{Min: 1000, Fail: true}
+	_, err = AuditURL(context.Background(), config)
+	if !IsBenchmarkError(err) {
+		t.Errorf("expected benchmark error, got %v", err)
+	}
+	// the report of the last attempt is set aside
+	if _, err := os.Stat("slow.json"); !os.IsNotExist(err) {
+		t.Errorf("expected slow.json to be moved, got %v", err)
+	}
+	if _, err := os.Stat("slow.broken.json"); err != nil {
+		t.Errorf("expected slow.broken.json, got %v", err)
+	}
+}
+
+// TestBenchmarkRangeValidate checks the accepted ranges.
+func TestBenchmarkRangeValidate(t *testing.T) {
+	testCases := []struct {
+		r     BenchmarkRange
+		valid bool
+	}{
+		{BenchmarkRange{}, true},
+		{BenchmarkRange{Min: 500}, true},
+		{BenchmarkRange{Min: 500, Max: 1500}, true},
+		{BenchmarkRange{Min: 1500, Max: 500}, false},
+		{BenchmarkRange{Min: -1}, false},
+	}
+
+	for _, tc := range testCases {
+		err := tc.r.Validate()
+		if tc.valid && err != nil {
+			t.Errorf("%+v: unexpected error %s", tc.r, err)
+		} else if !tc.valid && !IsInvalidConfigError(err) {
+			t.Errorf("%+v: expected invalid config error, got %v", tc.r, err)
+		}
+	}
+}
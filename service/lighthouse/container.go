@@ -0,0 +1,230 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/lighthouse-keeper/service/docker"
+)
+
+// containerRunner runs lighthouse in a container using a Docker compatible
+// command line client like docker or podman.
+type containerRunner struct {
+	binary       string
+	dockerLinks  []string
+	network      string
+	extraHosts   []string
+	image        string
+	imageArchive string
+	pull         string
+	user         string
+	resources    Resources
+
+	// imageDigest is resolved by Prepare.
+	imageDigest string
+	// runAs and chownTo are resolved by Prepare, see containerUser.
+	runAs   string
+	chownTo string
+}
+
+func newContainerRunner(binary string, config RunnerConfig) *containerRunner {
+	pull := config.Pull
+	if pull == "" {
+		pull = PullMissing
+	}
+
+	return &containerRunner{
+		binary:       binary,
+		dockerLinks:  config.DockerLinks,
+		network:      config.Network,
+		extraHosts:   config.ExtraHosts,
+		image:        imageReference(config),
+		imageArchive: config.ImageArchive,
+		pull:         pull,
+		user:         config.User,
+		resources:    config.Resources,
+	}
+}
+
+// Prepare makes the image available according to the pull policy and
+// decides which user to run lighthouse as.
+func (r *containerRunner) Prepare(ctx context.Context, out io.Writer) error {
+	digest, err := r.prepareImage(ctx, out)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.imageDigest = digest
+	printImage(out, r.image, r.imageDigest)
+
+	rootless, err := r.isRootless(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.runAs, r.chownTo = containerUser(r.user, os.Getuid(), os.Getgid(), rootless)
+	if r.runAs != "" {
+		fmt.Fprintf(out, "Running lighthouse as user %s\n", r.runAs)
+	}
+
+	return nil
+}
+
+// Metadata returns the runtime and the resolved image.
+func (r *containerRunner) Metadata() Metadata {
+	meta := Metadata{
+		Runtime:     r.binary,
+		Image:       r.image,
+		ImageDigest: r.imageDigest,
+	}
+	if !r.resources.IsZero() {
+		resources := r.resources
+		meta.Resources = &resources
+	}
+
+	return meta
+}
+
+// Run executes lighthouse in a new container. The job's work directory is
+// mounted as the container's working directory. If ctx is done before
+// lighthouse finishes, the container is removed. Files written by a container
+// running as root are handed over to the invoking user afterwards if
+// configured.
+func (r *containerRunner) Run(ctx context.Context, job Job) error {
+	tmpDir, err := sharedMemoryDir(r.runAs)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = handOverSecrets(job.SecretMounts, r.runAs)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	args := []string{
+		"--tty",
+		fmt.Sprintf("-v=%s:/workdir", job.WorkDir),
+		"-w=/workdir",
+	}
+	if r.resources.ShmSize == 0 {
+		args = append(args, fmt.Sprintf("-v=%s:/dev/shm", tmpDir))
+	}
+	args = append(args, r.resources.args()...)
+	for _, m := range append(append([]string{}, job.Mounts...), job.SecretMounts...) {
+		args = append(args, fmt.Sprintf("-v=%s:%s:ro", m, m))
+	}
+	args = append(args, r.userArgs()...)
+	args = append(args, r.networkArgs()...)
+	command := "lighthouse"
+	if job.Command != "" {
+		command = job.Command
+	}
+	args = append(args, r.image, command)
+	args = append(args, job.Args...)
+
+	_, stderr, err := r.runContainer(ctx, args, job.Output)
+	if r.chownTo != "" && job.Name != "" {
+		chownErr := r.chownOutputs(job)
+		if err == nil && chownErr != nil {
+			return microerror.Mask(chownErr)
+		} else if chownErr != nil {
+			fmt.Fprintf(job.Output, "%s\n", chownErr)
+		}
+	}
+	if IsRunFailedError(err) {
+		fmt.Fprintf(job.Output, "%s\n", stderr)
+		return microerror.Mask(err)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Exec runs a command in a new container of the lighthouse image, with the
+// same network settings as lighthouse, and returns its output.
+func (r *containerRunner) Exec(ctx context.Context, args []string) ([]byte, error) {
+	runArgs := r.networkArgs()
+	runArgs = append(runArgs, r.image)
+	runArgs = append(runArgs, args...)
+
+	stdout, stderr, err := r.runContainer(ctx, runArgs, ioutil.Discard)
+	if IsRunFailedError(err) {
+		return nil, microerror.Maskf(err, "%s", bytes.TrimSpace(stderr))
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return stdout, nil
+}
+
+// networkArgs returns the docker run flags connecting a container to the
+// network of the audited site.
+func (r *containerRunner) networkArgs() []string {
+	args := []string{}
+	if r.network != "" {
+		args = append(args, fmt.Sprintf("--network=%s", r.network))
+	}
+	for _, l := range r.dockerLinks {
+		args = append(args, fmt.Sprintf("--link=%s", l))
+	}
+	for _, h := range r.extraHosts {
+		args = append(args, fmt.Sprintf("--add-host=%s", h))
+	}
+
+	return args
+}
+
+// runContainer runs a new, named container with the given docker run flags
+// and returns its output. If ctx is done before the container exits, it is
+// removed.
+func (r *containerRunner) runContainer(ctx context.Context, args []string, out io.Writer) ([]byte, []byte, error) {
+	name, err := docker.RandomName()
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	args = append([]string{"run", "--rm", fmt.Sprintf("--name=%s", name)}, args...)
+
+	command := exec.Command(r.binary, args...)
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	err = command.Start()
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- command.Wait()
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// Killing the client would leave the container running, so we
+		// remove the container and wait for the client to return.
+		fmt.Fprintf(out, "Removing container %s\n", name)
+		_, rmErr := r.command(context.Background(), "rm", "--force", name)
+		if rmErr != nil {
+			fmt.Fprintf(out, "Could not remove container %s: %s\n", name, rmErr)
+		}
+		<-done
+		return nil, nil, contextError(ctx)
+	}
+
+	if err != nil {
+		return stdout.Bytes(), stderr.Bytes(), microerror.Maskf(runFailedError, "%s run failed with %s", r.binary, err)
+	}
+
+	return stdout.Bytes(), stderr.Bytes(), nil
+}
@@ -0,0 +1,217 @@
+package lighthouse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Form factors lighthouse can emulate.
+const (
+	FormFactorDesktop = "desktop"
+	FormFactorMobile  = "mobile"
+)
+
+// FormFactorBoth selects both form factors in ParseFormFactors.
+const FormFactorBoth = "both"
+
+// Throttling methods supported by lighthouse.
+const (
+	ThrottlingSimulate = "simulate"
+	ThrottlingDevtools = "devtools"
+	ThrottlingProvided = "provided"
+)
+
+// Throttling describes the network and CPU throttling. Zero values leave
+// the lighthouse defaults in place.
+type Throttling struct {
+	Method         string  `json:"method,omitempty"`
+	RTTMs          float64 `json:"rttMs,omitempty"`
+	ThroughputKbps float64 `json:"throughputKbps,omitempty"`
+	CPUSlowdown    float64 `json:"cpuSlowdownMultiplier,omitempty"`
+}
+
+// Screen describes the emulated screen. Zero values keep the default screen
+// of the form factor.
+type Screen struct {
+	Width             int     `json:"width,omitempty"`
+	Height            int     `json:"height,omitempty"`
+	DeviceScaleFactor float64 `json:"deviceScaleFactor,omitempty"`
+}
+
+// Preset is a named combination of form factor, throttling and screen.
+type Preset struct {
+	// FormFactor is empty if the preset works with any form factor.
+	FormFactor string
+	Throttling Throttling
+	Screen     Screen
+}
+
+// Presets are the named emulation presets.
+var Presets = map[string]Preset{
+	"mobile-slow-4g": {
+		FormFactor: FormFactorMobile,
+		Throttling: Throttling{Method: ThrottlingSimulate, RTTMs: 150, ThroughputKbps: 1638.4, CPUSlowdown: 4},
+		Screen:     Screen{Width: 360, Height: 640, DeviceScaleFactor: 2.625},
+	},
+	"mobile-regular-3g": {
+		FormFactor: FormFactorMobile,
+		Throttling: Throttling{Method: ThrottlingSimulate, RTTMs: 300, ThroughputKbps: 700, CPUSlowdown: 4},
+		Screen:     Screen{Width: 360, Height: 640, DeviceScaleFactor: 2.625},
+	},
+	"desktop-cable": {
+		FormFactor: FormFactorDesktop,
+		Throttling: Throttling{Method: ThrottlingSimulate, RTTMs: 40, ThroughputKbps: 10240, CPUSlowdown: 1},
+		Screen:     Screen{Width: 1350, Height: 940, DeviceScaleFactor: 1},
+	},
+	"no-throttling": {
+		Throttling: Throttling{Method: ThrottlingProvided, CPUSlowdown: 1},
+	},
+}
+
+// PresetNames returns the names of all presets, sorted.
+func PresetNames() []string {
+	names := []string{}
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// GetPreset returns the preset with the given name.
+func GetPreset(name string) (Preset, error) {
+	p, ok := Presets[name]
+	if !ok {
+		return Preset{}, microerror.Maskf(invalidConfigError, "unknown preset %q, must be one of %s", name, strings.Join(PresetNames(), ", "))
+	}
+
+	return p, nil
+}
+
+// ValidateFormFactor checks that the form factor is known.
+func ValidateFormFactor(formFactor string) error {
+	if formFactor != FormFactorDesktop && formFactor != FormFactorMobile {
+		return microerror.Maskf(invalidConfigError, "unknown form factor %q, must be %q or %q", formFactor, FormFactorDesktop, FormFactorMobile)
+	}
+
+	return nil
+}
+
+// ParseFormFactors parses a comma separated list of form factors, or
+// FormFactorBoth for desktop and mobile. Duplicates are removed.
+func ParseFormFactors(s string) ([]string, error) {
+	if strings.TrimSpace(s) == FormFactorBoth {
+		return []string{FormFactorDesktop, FormFactorMobile}, nil
+	}
+
+	formFactors := []string{}
+	seen := map[string]bool{}
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		err := ValidateFormFactor(f)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !seen[f] {
+			seen[f] = true
+			formFactors = append(formFactors, f)
+		}
+	}
+
+	return formFactors, nil
+}
+
+// Validate checks the throttling method and values.
+func (t Throttling) Validate() error {
+	switch t.Method {
+	case "", ThrottlingSimulate, ThrottlingDevtools, ThrottlingProvided:
+	default:
+		return microerror.Maskf(invalidConfigError, "unknown throttling method %q, must be one of %q, %q or %q", t.Method, ThrottlingSimulate, ThrottlingDevtools, ThrottlingProvided)
+	}
+
+	if t.RTTMs < 0 || t.ThroughputKbps < 0 {
+		return microerror.Maskf(invalidConfigError, "throttling RTT and throughput must not be negative")
+	}
+	if t.CPUSlowdown != 0 && t.CPUSlowdown < 1 {
+		return microerror.Maskf(invalidConfigError, "CPU slowdown multiplier must be 1 or greater")
+	}
+
+	return nil
+}
+
+// Validate checks the screen dimensions.
+func (s Screen) Validate() error {
+	if s.Width < 0 || s.Height < 0 || s.DeviceScaleFactor < 0 {
+		return microerror.Maskf(invalidConfigError, "screen width, height and device scale factor must not be negative")
+	}
+
+	return nil
+}
+
+// args returns the lighthouse flags for the throttling.
+func (t Throttling) args() []string {
+	args := []string{}
+	if t.Method != "" {
+		args = append(args, fmt.Sprintf("--throttling-method=%s", t.Method))
+	}
+	if t.RTTMs != 0 {
+		args = append(args, fmt.Sprintf("--throttling.rttMs=%g", t.RTTMs))
+		if t.Method == ThrottlingDevtools {
+			args = append(args, fmt.Sprintf("--throttling.requestLatencyMs=%g", t.RTTMs))
+		}
+	}
+	if t.ThroughputKbps != 0 {
+		args = append(args, fmt.Sprintf("--throttling.throughputKbps=%g", t.ThroughputKbps))
+		if t.Method == ThrottlingDevtools {
+			args = append(args, fmt.Sprintf("--throttling.downloadThroughputKbps=%g", t.ThroughputKbps))
+		}
+	}
+	if t.CPUSlowdown != 0 {
+		args = append(args, fmt.Sprintf("--throttling.cpuSlowdownMultiplier=%g", t.CPUSlowdown))
+	}
+
+	return args
+}
+
+// defaultScreens are the screens emulated for each form factor. Lighthouse
+// 7 and later emulate a mobile screen unless told otherwise, so desktop
+// audits get the screen of lighthouse's own desktop config.
+var defaultScreens = map[string]Screen{
+	FormFactorDesktop: {Width: 1350, Height: 940, DeviceScaleFactor: 1},
+}
+
+// formFactorArgs returns the lighthouse flags selecting the form factor and
+// its screen emulation. Values of screen override the defaults of the form
+// factor. The flags are those of lighthouse 7 and later.
+func formFactorArgs(formFactor string, screen Screen) []string {
+	defaults := defaultScreens[formFactor]
+	if screen.Width == 0 {
+		screen.Width = defaults.Width
+	}
+	if screen.Height == 0 {
+		screen.Height = defaults.Height
+	}
+	if screen.DeviceScaleFactor == 0 {
+		screen.DeviceScaleFactor = defaults.DeviceScaleFactor
+	}
+
+	args := []string{
+		fmt.Sprintf("--form-factor=%s", formFactor),
+		fmt.Sprintf("--screenEmulation.mobile=%t", formFactor == FormFactorMobile),
+	}
+	if screen.Width != 0 {
+		args = append(args, fmt.Sprintf("--screenEmulation.width=%d", screen.Width))
+	}
+	if screen.Height != 0 {
+		args = append(args, fmt.Sprintf("--screenEmulation.height=%d", screen.Height))
+	}
+	if screen.DeviceScaleFactor != 0 {
+		args = append(args, fmt.Sprintf("--screenEmulation.deviceScaleFactor=%g", screen.DeviceScaleFactor))
+	}
+
+	return args
+}
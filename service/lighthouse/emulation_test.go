@@ -0,0 +1,200 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+// TestThrottlingValidate checks the validation of throttling settings.
+func TestThrottlingValidate(t *testing.T) {
+	testCases := []struct {
+		throttling Throttling
+		valid      bool
+	}{
+		{Throttling{}, true},
+		{Throttling{Method: ThrottlingDevtools, RTTMs: 40, ThroughputKbps: 10240, CPUSlowdown: 1}, true},
+		{Throttling{Method: "fast"}, false},
+		{Throttling{RTTMs: -1}, false},
+		{Throttling{CPUSlowdown: 0.5}, false},
+	}
+
+	for _, tc := range testCases {
+		err := tc.throttling.Validate()
+		if tc.valid && err != nil {
+			t.Errorf("%+v: unexpected error %s", tc.throttling, err)
+		}
+		if !tc.valid && !IsInvalidConfigError(err) {
+			t.Errorf("%+v: expected invalidConfigError, got %v", tc.throttling, err)
+		}
+	}
+
+	for name, preset := range Presets {
+		if err := preset.Throttling.Validate(); err != nil {
+			t.Errorf("preset %q: %s", name, err)
+		}
+	}
+}
+
+// TestEmulationArgs checks the lighthouse flags for throttling and screen.
+func TestEmulationArgs(t *testing.T) {
+	preset, err := GetPreset("mobile-slow-4g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"--throttling-method=simulate",
+		"--throttling.rttMs=150",
+		"--throttling.throughputKbps=1638.4",
+		"--throttling.cpuSlowdownMultiplier=4",
+	}
+	if args := preset.Throttling.args(); !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+
+	expected = []string{
+		"--form-factor=mobile",
+		"--screenEmulation.mobile=true",
+		"--screenEmulation.width=360",
+		"--screenEmulation.height=640",
+		"--screenEmulation.deviceScaleFactor=2.625",
+	}
+	if args := formFactorArgs(preset.FormFactor, preset.Screen); !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+
+	expected = []string{"--form-factor=mobile", "--screenEmulation.mobile=true"}
+	if args := formFactorArgs(FormFactorMobile, Screen{}); !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+
+	_, err = GetPreset("mobile-5g")
+	if !IsInvalidConfigError(err) {
+		t.Errorf("expected invalidConfigError, got %v", err)
+	}
+}
+
+// TestAuditURLArgs checks the complete lighthouse command line for form
+// factors and emulation settings. The flags must all be understood by the
+// same lighthouse version.
+func TestAuditURLArgs(t *testing.T) {
+	testCases := []struct {
+		name       string
+		formFactor string
+		throttling Throttling
+		screen     Screen
+		expected   []string
+	}{
+		{
+			name:       "desktop",
+			formFactor: FormFactorDesktop,
+			expected: []string{
+				"--quiet",
+				"--no-enable-error-reporting",
+				"--chrome-flags=--no-sandbox --headless ",
+				"--output-path=example.json",
+				"--output=json",
+				"--form-factor=desktop",
+				"--screenEmulation.mobile=false",
+				"--screenEmulation.width=1350",
+				"--screenEmulation.height=940",
+				"--screenEmulation.deviceScaleFactor=1",
+				"https://example.com/",
+			},
+		},
+		{
+			name:       "mobile",
+			formFactor: FormFactorMobile,
+			expected: []string{
+				"--quiet",
+				"--no-enable-error-reporting",
+				"--chrome-flags=--no-sandbox --headless ",
+				"--output-path=example.json",
+				"--output=json",
+				"--form-factor=mobile",
+				"--screenEmulation.mobile=true",
+				"https://example.com/",
+			},
+		},
+		{
+			name:       "desktop with custom screen and throttling",
+			formFactor: FormFactorDesktop,
+			throttling: Throttling{Method: ThrottlingDevtools, RTTMs: 40},
+			screen:     Screen{Width: 1920},
+			expected: []string{
+				"--quiet",
+				"--no-enable-error-reporting",
+				"--chrome-flags=--no-sandbox --headless ",
+				"--output-path=example.json",
+				"--output=json",
+				"--form-factor=desktop",
+				"--screenEmulation.mobile=false",
+				"--screenEmulation.width=1920",
+				"--screenEmulation.height=940",
+				"--screenEmulation.deviceScaleFactor=1",
+				"--throttling-method=devtools",
+				"--throttling.rttMs=40",
+				"--throttling.requestLatencyMs=40",
+				"https://example.com/",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := newFakeRunner(t)
+
+			defer inTempDir(t)()
+
+			config := Config{
+				URL:        "https://example.com/",
+				Name:       "example",
+				FormFactor: tc.formFactor,
+				Throttling: tc.throttling,
+				Screen:     tc.screen,
+				Runner:     runner,
+				Output:     &bytes.Buffer{},
+			}
+
+			_, err := AuditURL(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if args := runner.jobs[0].Args; !reflect.DeepEqual(args, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, args)
+			}
+		})
+	}
+}
+
+// TestParseFormFactors checks parsing lists of form factors.
+func TestParseFormFactors(t *testing.T) {
+	testCases := []struct {
+		s        string
+		expected []string
+		valid    bool
+	}{
+		{"desktop", []string{FormFactorDesktop}, true},
+		{"both", []string{FormFactorDesktop, FormFactorMobile}, true},
+		{"mobile,desktop", []string{FormFactorMobile, FormFactorDesktop}, true},
+		{"mobile, mobile", []string{FormFactorMobile}, true},
+		{"tablet", nil, false},
+		{"desktop,", nil, false},
+	}
+
+	for _, tc := range testCases {
+		formFactors, err := ParseFormFactors(tc.s)
+		if !tc.valid {
+			if !IsInvalidConfigError(err) {
+				t.Errorf("%q: expected invalidConfigError, got %v", tc.s, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(formFactors, tc.expected) {
+			t.Errorf("%q: expected %q, got %q and %v", tc.s, tc.expected, formFactors, err)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/lighthouse-keeper/service/docker"
+	"github.com/giantswarm/lighthouse-keeper/service/engine"
+)
+
+// containerLabel marks the containers created by lighthouse-keeper, so
+// containers left behind by a killed run can be cleaned up.
+const containerLabel = "io.giantswarm.lighthouse-keeper"
+
+// engineRunner runs lighthouse in a Docker container created through the
+// Docker Engine API.
+type engineRunner struct {
+	client       *engine.Client
+	dockerLinks  []string
+	network      string
+	extraHosts   []string
+	image        string
+	imageArchive string
+	pull         string
+	user         string
+	resources    Resources
+
+	// imageDigest is resolved by Prepare.
+	imageDigest string
+	// runAs and chownTo are resolved by Prepare, see containerUser.
+	runAs   string
+	chownTo string
+}
+
+func newEngineRunner(config RunnerConfig) (*engineRunner, error) {
+	client, err := engine.New(engine.Config{})
+	if engine.IsInvalidConfigError(err) {
+		return nil, microerror.Maskf(invalidConfigError, "%s", err)
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	pull := config.Pull
+	if pull == "" {
+		pull = PullMissing
+	}
+
+	return &engineRunner{
+		client:       client,
+		dockerLinks:  config.DockerLinks,
+		network:      config.Network,
+		extraHosts:   config.ExtraHosts,
+		image:        imageReference(config),
+		imageArchive: config.ImageArchive,
+		pull:         pull,
+		user:         config.User,
+		resources:    config.Resources,
+	}, nil
+}
+
+// Prepare removes stopped containers of earlier runs, makes the image
+// available according to the pull policy and decides which user to run
+// lighthouse as.
+func (r *engineRunner) Prepare(ctx context.Context, out io.Writer) error {
+	err := r.removeStaleContainers(ctx, out)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	digest, err := r.prepareImage(ctx, out)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.imageDigest = digest
+	printImage(out, r.image, r.imageDigest)
+
+	info, err := r.client.Info(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.runAs, r.chownTo = containerUser(r.user, os.Getuid(), os.Getgid(), info.Rootless())
+	if r.runAs != "" {
+		fmt.Fprintf(out, "Running lighthouse as user %s\n", r.runAs)
+	}
+
+	return nil
+}
+
+// Metadata returns the runtime and the resolved image.
+func (r *engineRunner) Metadata() Metadata {
+	meta := Metadata{
+		Runtime:     RuntimeDockerAPI,
+		Image:       r.image,
+		ImageDigest: r.imageDigest,
+	}
+	if !r.resources.IsZero() {
+		resources := r.resources
+		meta.Resources = &resources
+	}
+
+	return meta
+}
+
+// Run executes lighthouse in a new container and streams its output to the
+// job's Output. The job's work directory is mounted as the container's
+// working directory. If ctx is done before
+// lighthouse finishes, the container is removed. Files written by a container
+// running as root are handed over to the invoking user afterwards if
+// configured.
+func (r *engineRunner) Run(ctx context.Context, job Job) error {
+	tmpDir, err := sharedMemoryDir(r.runAs)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = handOverSecrets(job.SecretMounts, r.runAs)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	name, err := docker.RandomName()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.client.Run(ctx, name, r.containerConfig(job, tmpDir), job.Output, job.Output)
+	if r.chownTo != "" && job.Name != "" {
+		chownErr := r.chownOutputs(job)
+		if err == nil && chownErr != nil {
+			return microerror.Mask(chownErr)
+		} else if chownErr != nil {
+			fmt.Fprintf(job.Output, "%s\n", chownErr)
+		}
+	}
+	if engine.IsCanceledError(err) {
+		fmt.Fprintf(job.Output, "Removed container %s\n", name)
+	} else if engine.IsRemoveError(err) {
+		fmt.Fprintf(job.Output, "%s\n", err)
+	}
+	if err != nil {
+		return r.runError(ctx, err)
+	}
+
+	return nil
+}
+
+// Exec runs a command in a new container of the lighthouse image, with the
+// same network settings as lighthouse, and returns its output.
+func (r *engineRunner) Exec(ctx context.Context, args []string) ([]byte, error) {
+	name, err := docker.RandomName()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	config := engine.ContainerConfig{
+		Image:      r.image,
+		Cmd:        args,
+		Labels:     map[string]string{containerLabel: "true"},
+		HostConfig: r.hostConfig(),
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = r.client.Run(ctx, name, config, &stdout, &stderr)
+	if err != nil {
+		return nil, microerror.Maskf(r.runError(ctx, err), "%s", bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// containerConfig returns the container running the job, with tmpDir as its
+// shared memory.
+func (r *engineRunner) containerConfig(job Job, tmpDir string) engine.ContainerConfig {
+	command := "lighthouse"
+	if job.Command != "" {
+		command = job.Command
+	}
+
+	config := engine.ContainerConfig{
+		Image:      r.image,
+		Cmd:        append([]string{command}, job.Args...),
+		WorkingDir: "/workdir",
+		Labels:     map[string]string{containerLabel: "true"},
+		HostConfig: r.hostConfig(),
+	}
+
+	config.HostConfig.Binds = []string{fmt.Sprintf("%s:/workdir", job.WorkDir)}
+	if r.resources.ShmSize > 0 {
+		config.HostConfig.ShmSize = r.resources.ShmSize
+	} else {
+		config.HostConfig.Binds = append(config.HostConfig.Binds, fmt.Sprintf("%s:/dev/shm", tmpDir))
+	}
+	config.HostConfig.NanoCpus = int64(r.resources.CPUs * 1e9)
+	config.HostConfig.Memory = r.resources.Memory
+	config.HostConfig.CpusetCpus = r.resources.CPUSet
+	for _, m := range append(append([]string{}, job.Mounts...), job.SecretMounts...) {
+		config.HostConfig.Binds = append(config.HostConfig.Binds, fmt.Sprintf("%s:%s:ro", m, m))
+	}
+
+	if r.runAs != "" {
+		config.User = r.runAs
+		// the user likely has no home directory in the image, Chrome
+		// needs a writable one
+		config.Env = []string{"HOME=/tmp"}
+	}
+
+	return config
+}
+
+// hostConfig returns the settings connecting a container to the network of
+// the audited site.
+func (r *engineRunner) hostConfig() engine.HostConfig {
+	return engine.HostConfig{
+		NetworkMode: r.network,
+		Links:       r.dockerLinks,
+		ExtraHosts:  r.extraHosts,
+	}
+}
+
+// runError translates errors of running a container.
+func (r *engineRunner) runError(ctx context.Context, err error) error {
+	switch {
+	case ctx.Err() != nil:
+		return contextError(ctx)
+	case engine.IsImageNotFoundError(err):
+		return microerror.Maskf(imageError, "%s", err)
+	case engine.IsOOMKilledError(err):
+		return microerror.Maskf(runFailedError, "%s, Chrome needs more memory for this page", err)
+	case engine.IsExitError(err):
+		return microerror.Maskf(runFailedError, "%s", err)
+	}
+
+	return microerror.Mask(err)
+}
+
+// chownOutputs hands the files lighthouse wrote for the job over to the
+// invoking user. Only root may change their owner, whichever user the image
+// is configured with.
+func (r *engineRunner) chownOutputs(job Job) error {
+	name, err := docker.RandomName()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	config := engine.ContainerConfig{
+		Image:  r.image,
+		Cmd:    chownCommand(job, r.chownTo),
+		User:   "0",
+		Labels: map[string]string{containerLabel: "true"},
+		HostConfig: engine.HostConfig{
+			Binds: []string{fmt.Sprintf("%s:/workdir", job.WorkDir)},
+		},
+	}
+
+	err = r.client.Run(context.Background(), name, config, job.Output, job.Output)
+	if err != nil {
+		return microerror.Maskf(err, "changing the owner of the reports to %s", r.chownTo)
+	}
+
+	return nil
+}
+
+// removeStaleContainers removes stopped containers created by earlier runs
+// that were killed before they could clean up. Running containers may
+// belong to a concurrent run and are left alone.
+func (r *engineRunner) removeStaleContainers(ctx context.Context, out io.Writer) error {
+	containers, err := r.client.ListContainers(ctx, containerLabel)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, c := range containers {
+		if c.State != "exited" && c.State != "dead" {
+			continue
+		}
+
+		fmt.Fprintf(out, "Removing container %s left behind by an earlier run\n", c.ID)
+		err = r.client.RemoveContainer(ctx, c.ID)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// prepareImage loads or pulls the image according to the pull policy and
+// returns the resolved image digest.
+func (r *engineRunner) prepareImage(ctx context.Context, out io.Writer) (string, error) {
+	if r.imageArchive != "" {
+		fmt.Fprintf(out, "Loading image from %s\n", r.imageArchive)
+		err := r.loadArchive(ctx, out)
+		if err != nil {
+			return "", microerror.Maskf(imageError, "loading image archive %q: %s", r.imageArchive, err)
+		}
+	}
+
+	_, inspectErr := r.client.InspectImage(ctx, r.image)
+	if inspectErr != nil && !engine.IsImageNotFoundError(inspectErr) {
+		return "", microerror.Mask(inspectErr)
+	}
+
+	switch {
+	case r.pull == PullAlways, r.pull == PullMissing && inspectErr != nil && r.imageArchive == "":
+		fmt.Fprintf(out, "Pulling image %s\n", r.image)
+		err := r.client.PullImage(ctx, r.image, out)
+		if err != nil {
+			return "", microerror.Maskf(imageError, "%s", err)
+		}
+	case inspectErr != nil:
+		return "", microerror.Maskf(imageError, "image %q is not available locally", r.image)
+	}
+
+	image, err := r.client.InspectImage(ctx, r.image)
+	if err != nil {
+		return "", microerror.Maskf(imageError, "resolving digest of image %q: %s", r.image, err)
+	}
+
+	return image.Digest(r.image), nil
+}
+
+func (r *engineRunner) loadArchive(ctx context.Context, out io.Writer) error {
+	f, err := os.Open(r.imageArchive)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer f.Close()
+
+	err = r.client.LoadImage(ctx, f, out)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package lighthouse
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestEngineContainerConfig checks the container created for a lighthouse
+// job.
+func TestEngineContainerConfig(t *testing.T) {
+	r := &engineRunner{
+		image:      "quay.io/giantswarm/lighthouse:latest",
+		network:    "site",
+		extraHosts: []string{"host.docker.internal:host-gateway"},
+		runAs:      "1000:100",
+	}
+	job := Job{
+		WorkDir: "/home/ci/reports",
+		Command: "node",
+		Args:    []string{"-e", "script", "https://example.com/"},
+		Mounts:  []string{"/home/ci/config"},
+	}
+
+	config := r.containerConfig(job, "/tmp/lighthouse-temp1")
+
+	if !reflect.DeepEqual(config.Cmd, []string{"node", "-e", "script", "https://example.com/"}) {
+		t.Errorf("unexpected command %q", config.Cmd)
+	}
+	expectedBinds := []string{"/home/ci/reports:/workdir", "/tmp/lighthouse-temp1:/dev/shm", "/home/ci/config:/home/ci/config:ro"}
+	if !reflect.DeepEqual(config.HostConfig.Binds, expectedBinds) {
+		t.Errorf("expected binds %q, got %q", expectedBinds, config.HostConfig.Binds)
+	}
+	if config.WorkingDir != "/workdir" || config.HostConfig.NetworkMode != "site" || len(config.HostConfig.ExtraHosts) != 1 {
+		t.Errorf("unexpected settings %+v", config)
+	}
+	if config.User != "1000:100" || !reflect.DeepEqual(config.Env, []string{"HOME=/tmp"}) {
+		t.Errorf("expected to run as 1000:100 with a writable home, got %q and %q", config.User, config.Env)
+	}
+	if config.Labels[containerLabel] != "true" {
+		t.Errorf("expected the container to be labeled, got %v", config.Labels)
+	}
+
+	r.runAs = ""
+	job.Command = ""
+	config = r.containerConfig(job, "/tmp/lighthouse-temp1")
+	if config.Cmd[0] != "lighthouse" || config.User != "" || len(config.Env) != 0 {
+		t.Errorf("expected lighthouse as the image's user, got %q as %q with %q", config.Cmd[0], config.User, config.Env)
+	}
+
+	// a sized /dev/shm replaces the mounted host directory
+	r.resources = Resources{CPUs: 1.5, Memory: 2 << 30, CPUSet: "0-1", ShmSize: 1 << 30}
+	config = r.containerConfig(job, "/tmp/lighthouse-temp1")
+	expectedBinds = []string{"/home/ci/reports:/workdir", "/home/ci/config:/home/ci/config:ro"}
+	if !reflect.DeepEqual(config.HostConfig.Binds, expectedBinds) {
+		t.Errorf("expected binds %q, got %q", expectedBinds, config.HostConfig.Binds)
+	}
+	h := config.HostConfig
+	if h.NanoCpus != 1500000000 || h.Memory != 2<<30 || h.CpusetCpus != "0-1" || h.ShmSize != 1<<30 {
+		t.Errorf("unexpected limits %+v", h)
+	}
+}
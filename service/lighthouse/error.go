@@ -0,0 +1,106 @@
+package lighthouse
+
+import "github.com/giantswarm/microerror"
+
+// unknownMetricError is used when the metric to pick the median run by is
+// neither a category nor an audit of the report
+var unknownMetricError = &microerror.Error{
+	Kind: "unknownMetricError",
+}
+
+// IsUnknownMetricError asserts unknownMetricError
+func IsUnknownMetricError(err error) bool {
+	return microerror.Cause(err) == unknownMetricError
+}
+
+// invalidConfigError is used when the lighthouse configuration is invalid
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfigError asserts invalidConfigError
+func IsInvalidConfigError(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+// runFailedError is used when lighthouse exits with an error
+var runFailedError = &microerror.Error{
+	Kind: "runFailedError",
+}
+
+// IsRunFailedError asserts runFailedError
+func IsRunFailedError(err error) bool {
+	return microerror.Cause(err) == runFailedError
+}
+
+// imageError is used when the lighthouse image can not be loaded, pulled or
+// inspected
+var imageError = &microerror.Error{
+	Kind: "imageError",
+}
+
+// IsImageError asserts imageError
+func IsImageError(err error) bool {
+	return microerror.Cause(err) == imageError
+}
+
+// timeoutError is used when a lighthouse run exceeds its timeout
+var timeoutError = &microerror.Error{
+	Kind: "timeoutError",
+}
+
+// IsTimeoutError asserts timeoutError
+func IsTimeoutError(err error) bool {
+	return microerror.Cause(err) == timeoutError
+}
+
+// brokenReportError is used when lighthouse wrote a report for a broken run
+var brokenReportError = &microerror.Error{
+	Kind: "brokenReportError",
+}
+
+// IsBrokenReportError asserts brokenReportError
+func IsBrokenReportError(err error) bool {
+	return microerror.Cause(err) == brokenReportError
+}
+
+// notReadyError is used when the audit target does not become ready in time
+var notReadyError = &microerror.Error{
+	Kind: "notReadyError",
+}
+
+// IsNotReadyError asserts notReadyError
+func IsNotReadyError(err error) bool {
+	return microerror.Cause(err) == notReadyError
+}
+
+// loginScriptError is used when the login script run before the audit fails
+var loginScriptError = &microerror.Error{
+	Kind: "loginScriptError",
+}
+
+// IsLoginScriptError asserts loginScriptError
+func IsLoginScriptError(err error) bool {
+	return microerror.Cause(err) == loginScriptError
+}
+
+// benchmarkError is used when the benchmark index of a run is outside the
+// configured range
+var benchmarkError = &microerror.Error{
+	Kind: "benchmarkError",
+}
+
+// IsBenchmarkError asserts benchmarkError
+func IsBenchmarkError(err error) bool {
+	return microerror.Cause(err) == benchmarkError
+}
+
+// thresholdError is used when a report scores below a configured threshold
+var thresholdError = &microerror.Error{
+	Kind: "thresholdError",
+}
+
+// IsThresholdError asserts thresholdError
+func IsThresholdError(err error) bool {
+	return microerror.Cause(err) == thresholdError
+}
@@ -0,0 +1,160 @@
+package lighthouse
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Report formats lighthouse can write.
+const (
+	FormatJSON = "json"
+	FormatHTML = "html"
+	FormatCSV  = "csv"
+)
+
+// Formats are the supported report formats.
+var Formats = []string{FormatJSON, FormatHTML, FormatCSV}
+
+// ValidateFormats checks that all formats are supported.
+func ValidateFormats(formats []string) error {
+	for _, f := range formats {
+		supported := false
+		for _, s := range Formats {
+			if f == s {
+				supported = true
+			}
+		}
+		if !supported {
+			return microerror.Maskf(invalidConfigError, "output format %q must be one of %s", f, strings.Join(Formats, ", "))
+		}
+	}
+
+	return nil
+}
+
+// outputFormats returns the formats to write, always starting with JSON,
+// which the audit itself needs, without duplicates.
+func outputFormats(formats []string) []string {
+	result := []string{FormatJSON}
+	for _, f := range formats {
+		duplicate := false
+		for _, r := range result {
+			if f == r {
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			result = append(result, f)
+		}
+	}
+
+	return result
+}
+
+// outputArgs returns the lighthouse flags writing the formats and assets.
+func outputArgs(formats []string, saveAssets bool) []string {
+	args := []string{}
+	for _, f := range formats {
+		args = append(args, fmt.Sprintf("--output=%s", f))
+	}
+	if saveAssets {
+		args = append(args, "--save-assets")
+	}
+
+	return args
+}
+
+// collectOutputs renames the files lighthouse wrote for <name>.json in dir
+// to consistent names: <name>.<format> for every format and
+// <name>.trace.json and <name>.devtoolslog.json for the assets. With more
+// than one format, lighthouse writes <name>.report.<format> instead, and
+// it numbers assets by pass as <name>-<pass>.trace.json.
+func collectOutputs(dir, name string, formats []string, saveAssets bool) error {
+	if len(formats) > 1 {
+		for _, f := range formats {
+			from := filepath.Join(dir, fmt.Sprintf("%s.report.%s", name, f))
+			to := filepath.Join(dir, fmt.Sprintf("%s.%s", name, f))
+			err := os.Rename(from, to)
+			if err != nil {
+				return microerror.Maskf(brokenReportError, "lighthouse did not write the %s report: %s", f, err)
+			}
+		}
+	}
+
+	if !saveAssets {
+		return nil
+	}
+
+	assetDir := filepath.Join(dir, filepath.Dir(name))
+	files, err := ioutil.ReadDir(assetDir)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	base := filepath.Base(name)
+	assetExpr := regexp.MustCompile(`^` + regexp.QuoteMeta(base) + `-(\d+)\.(trace|devtoolslog)\.json$`)
+	for _, f := range files {
+		match := assetExpr.FindStringSubmatch(f.Name())
+		if match == nil {
+			continue
+		}
+
+		target := fmt.Sprintf("%s.%s.json", base, match[2])
+		if match[1] != "0" {
+			target = fmt.Sprintf("%s.%s-%s.json", base, match[2], match[1])
+		}
+
+		err := os.Rename(filepath.Join(assetDir, f.Name()), filepath.Join(assetDir, target))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// copyOutputs copies the non-JSON reports and the assets of run <from> to
+// <to>, for the median run.
+func copyOutputs(dir, from, to string, formats []string, saveAssets bool) error {
+	for _, f := range formats {
+		if f == FormatJSON {
+			continue
+		}
+		err := copyFile(filepath.Join(dir, from+"."+f), filepath.Join(dir, to+"."+f))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if saveAssets {
+		for _, a := range []string{"trace", "devtoolslog"} {
+			err := copyFile(filepath.Join(dir, from+"."+a+".json"), filepath.Join(dir, to+"."+a+".json"))
+			// lighthouse may not have saved all assets
+			if err != nil && !os.IsNotExist(microerror.Cause(err)) {
+				return microerror.Mask(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func copyFile(from, to string) error {
+	data, err := ioutil.ReadFile(from)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = ioutil.WriteFile(to, data, 0644)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestAuditURLFormats checks that all formats and assets are written with
+// consistent names, for single runs and for the median of several runs.
+func TestAuditURLFormats(t *testing.T) {
+	runner := newFakeRunner(t)
+	medianRunner := newFakeRunner(t)
+
+	defer inTempDir(t)()
+
+	config := Config{
+		URL:           "https://example.com/",
+		Name:          "single",
+		OutputFormats: []string{FormatHTML, FormatCSV, FormatHTML},
+		SaveAssets:    true,
+		Runner:        runner,
+		Output:        &bytes.Buffer{},
+	}
+
+	_, err := AuditURL(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := 0
+	for _, a := range runner.jobs[0].Args {
+		if strings.HasPrefix(a, "--output=") {
+			outputs++
+		}
+	}
+	if outputs != 3 {
+		t.Errorf("expected 3 output formats, got %d", outputs)
+	}
+
+	for _, name := range []string{"single.json", "single.html", "single.csv", "single.trace.json", "single.devtoolslog.json"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("expected file %q: %s", name, err)
+		}
+	}
+	for _, name := range []string{"single.report.html", "single-0.trace.json"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("expected file %q to be renamed", name)
+		}
+	}
+
+	// runs 1 and 3 use fixture 001, which is the median
+	config.Name = "median"
+	config.Runs = 3
+	config.Runner = medianRunner
+	_, err = AuditURL(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"median.run-2.html", "median.html", "median.csv", "median.trace.json"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("expected file %q: %s", name, err)
+		}
+	}
+	html, err := ioutil.ReadFile("median.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(html), "001.json") {
+		t.Errorf("expected HTML report of the median run, got %q", html)
+	}
+
+	config.OutputFormats = []string{"pdf"}
+	_, err = AuditURL(context.Background(), config)
+	if !IsInvalidConfigError(err) {
+		t.Errorf("expected invalid config error, got %v", err)
+	}
+}
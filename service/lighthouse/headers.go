@@ -0,0 +1,166 @@
+package lighthouse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// ParseHeader splits a header given as "Name: value".
+func ParseHeader(header string) (string, string, error) {
+	i := strings.Index(header, ":")
+	if i < 1 {
+		return "", "", microerror.Maskf(invalidConfigError, "header must have the form 'Name: value'")
+	}
+
+	name := strings.TrimSpace(header[:i])
+	if strings.ContainsAny(name, " \t") {
+		return "", "", microerror.Maskf(invalidConfigError, "header name %q must not contain whitespace", name)
+	}
+
+	return name, strings.TrimSpace(header[i+1:]), nil
+}
+
+// BasicAuthHeader returns the Authorization header value for credentials
+// given as "user:password".
+func BasicAuthHeader(credentials string) (string, error) {
+	if !strings.Contains(credentials, ":") {
+		return "", microerror.Maskf(invalidConfigError, "basic auth credentials must have the form 'user:password'")
+	}
+
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)), nil
+}
+
+// ReadCookieFile reads cookies from a file in Netscape cookies.txt format
+// or from a JSON file and returns them as a Cookie header value. The JSON
+// file may contain an array of objects with "name" and "value", like
+// browser extensions and Puppeteer export them, or an object mapping names
+// to values.
+func ReadCookieFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var cookies [][2]string
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		cookies, err = parseJSONCookies(trimmed)
+	} else {
+		cookies, err = parseNetscapeCookies(data)
+	}
+	if err != nil {
+		return "", microerror.Maskf(invalidConfigError, "cookie file %q: %s", path, err)
+	}
+	if len(cookies) == 0 {
+		return "", microerror.Maskf(invalidConfigError, "cookie file %q contains no cookies", path)
+	}
+
+	pairs := []string{}
+	for _, c := range cookies {
+		pairs = append(pairs, c[0]+"="+c[1])
+	}
+
+	return strings.Join(pairs, "; "), nil
+}
+
+func parseJSONCookies(data []byte) ([][2]string, error) {
+	cookies := [][2]string{}
+
+	if data[0] == '{' {
+		var m map[string]string
+		err := json.Unmarshal(data, &m)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		for name, value := range m {
+			cookies = append(cookies, [2]string{name, value})
+		}
+		sort.Slice(cookies, func(i, j int) bool { return cookies[i][0] < cookies[j][0] })
+		return cookies, nil
+	}
+
+	var list []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	err := json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for _, c := range list {
+		if c.Name == "" {
+			return nil, microerror.Newf("cookie without name")
+		}
+		cookies = append(cookies, [2]string{c.Name, c.Value})
+	}
+
+	return cookies, nil
+}
+
+// parseNetscapeCookies parses the tab separated cookies.txt format with the
+// fields domain, include subdomains, path, secure, expiry, name and value.
+func parseNetscapeCookies(data []byte) ([][2]string, error) {
+	cookies := [][2]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, microerror.Newf("line %d: expected 7 tab separated fields, got %d", lineNumber, len(fields))
+		}
+		cookies = append(cookies, [2]string{fields[5], fields[6]})
+	}
+
+	return cookies, nil
+}
+
+// headerNames returns the sorted names of the headers, to record them
+// without their secret values.
+func headerNames(headers map[string]string) []string {
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// writeExtraHeaders writes the headers to a JSON file in a new private
+// temporary directory, so they don't show up in any command line. The
+// directory must be removed by the caller.
+func writeExtraHeaders(headers map[string]string) (dir string, path string, err error) {
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return "", "", microerror.Mask(err)
+	}
+
+	dir, err = ioutil.TempDir("", "lighthouse-headers")
+	if err != nil {
+		return "", "", microerror.Mask(err)
+	}
+
+	path = filepath.Join(dir, "extra-headers.json")
+	err = ioutil.WriteFile(path, data, 0600)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", microerror.Mask(err)
+	}
+
+	return dir, path, nil
+}
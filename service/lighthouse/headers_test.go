@@ -0,0 +1,168 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseHeader checks splitting headers into name and value.
+func TestParseHeader(t *testing.T) {
+	testCases := []struct {
+		header        string
+		expectedName  string
+		expectedValue string
+		expectError   bool
+	}{
+		{header: "X-Token: abc", expectedName: "X-Token", expectedValue: "abc"},
+		{header: "X-Token:a:b:c ", expectedName: "X-Token", expectedValue: "a:b:c"},
+		{header: "X-Empty:", expectedName: "X-Empty", expectedValue: ""},
+		{header: "X-Token abc", expectError: true},
+		{header: ": abc", expectError: true},
+		{header: "X Token: abc", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		name, value, err := ParseHeader(tc.header)
+		if tc.expectError {
+			if !IsInvalidConfigError(err) {
+				t.Errorf("%q: expected invalid config error, got %v", tc.header, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tc.header, err)
+			continue
+		}
+		if name != tc.expectedName || value != tc.expectedValue {
+			t.Errorf("%q: expected %q %q, got %q %q", tc.header, tc.expectedName, tc.expectedValue, name, value)
+		}
+	}
+}
+
+// TestReadCookieFile checks the supported cookie file formats.
+func TestReadCookieFile(t *testing.T) {
+	testCases := []struct {
+		name        string
+		content     string
+		expected    string
+		expectError bool
+	}{
+		{
+			name: "netscape",
+			content: "# Netscape HTTP Cookie File\n\n" +
+				"example.com\tFALSE\t/\tTRUE\t0\tsession\tabc\n" +
+				"#HttpOnly_example.com\tFALSE\t/\tTRUE\t0\tsso\tdef\n",
+			expected: "session=abc; sso=def",
+		},
+		{
+			name:     "json list",
+			content:  `[{"name": "session", "value": "abc", "domain": "example.com"}, {"name": "sso", "value": "def"}]`,
+			expected: "session=abc; sso=def",
+		},
+		{
+			name:     "json object",
+			content:  `{"sso": "def", "session": "abc"}`,
+			expected: "session=abc; sso=def",
+		},
+		{
+			name:        "broken netscape",
+			content:     "example.com\tFALSE\t/\n",
+			expectError: true,
+		},
+		{
+			name:        "empty",
+			content:     "# Netscape HTTP Cookie File\n",
+			expectError: true,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "lighthouse-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, tc := range testCases {
+		path := filepath.Join(dir, strings.Replace(tc.name, " ", "-", -1))
+		err := ioutil.WriteFile(path, []byte(tc.content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cookies, err := ReadCookieFile(path)
+		if tc.expectError {
+			if !IsInvalidConfigError(err) {
+				t.Errorf("%d %s: expected invalid config error, got %v", i, tc.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d %s: unexpected error: %s", i, tc.name, err)
+			continue
+		}
+		if cookies != tc.expected {
+			t.Errorf("%d %s: expected %q, got %q", i, tc.name, tc.expected, cookies)
+		}
+	}
+}
+
+// TestAuditURLExtraHeaders checks that extra headers reach lighthouse via a
+// file and their values don't end up in the arguments or the metadata.
+func TestAuditURLExtraHeaders(t *testing.T) {
+	runner := newFakeRunner(t)
+
+	defer inTempDir(t)()
+
+	config := Config{
+		URL:    "https://example.com/",
+		Name:   "example",
+		Runner: runner,
+		Output: &bytes.Buffer{},
+		ExtraHeaders: map[string]string{
+			"Authorization": "Basic c2VjcmV0",
+			"Cookie":        "session=secret",
+		},
+	}
+
+	_, err := AuditURL(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.extraHeaders) != 1 || runner.extraHeaders[0] != `{"Authorization":"Basic c2VjcmV0","Cookie":"session=secret"}` {
+		t.Errorf("unexpected extra headers %v", runner.extraHeaders)
+	}
+
+	job := runner.jobs[0]
+	for _, a := range job.Args {
+		if strings.Contains(a, "secret") || strings.Contains(a, "c2VjcmV0") {
+			t.Errorf("argument %q contains a secret", a)
+		}
+		if strings.HasPrefix(a, "--extra-headers=") {
+			if _, err := os.Stat(strings.TrimPrefix(a, "--extra-headers=")); !os.IsNotExist(err) {
+				t.Errorf("expected extra headers file to be removed, got %v", err)
+			}
+		}
+	}
+
+	data, err := ioutil.ReadFile("example.meta.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") || strings.Contains(string(data), "c2VjcmV0") {
+		t.Errorf("metadata contains a secret: %s", data)
+	}
+
+	metadata, err := ReadMetadata("example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(metadata.Settings.ExtraHeaderNames, ",") != "Authorization,Cookie" {
+		t.Errorf("expected header names Authorization,Cookie, got %v", metadata.Settings.ExtraHeaderNames)
+	}
+}
@@ -0,0 +1,129 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/lighthouse-keeper/service/engine"
+)
+
+// Defaults for the container image providing Chrome and lighthouse.
+const (
+	DefaultImage    = "quay.io/giantswarm/lighthouse"
+	DefaultImageTag = "latest"
+)
+
+// Image pull policies.
+const (
+	PullAlways  = "always"
+	PullMissing = "missing"
+	PullNever   = "never"
+)
+
+// imageReference returns the full image reference to run, pinned by digest
+// if one is configured.
+func imageReference(config RunnerConfig) string {
+	image := config.Image
+	if image == "" {
+		image = DefaultImage
+	}
+
+	if config.ImageDigest != "" {
+		return fmt.Sprintf("%s@%s", image, config.ImageDigest)
+	}
+
+	// the image name may already carry a tag or digest
+	if strings.Contains(image, "@") || strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") {
+		return image
+	}
+
+	tag := config.ImageTag
+	if tag == "" {
+		tag = DefaultImageTag
+	}
+
+	return fmt.Sprintf("%s:%s", image, tag)
+}
+
+func validateImageConfig(config RunnerConfig) error {
+	switch config.Pull {
+	case PullAlways, PullMissing, PullNever, "":
+	default:
+		return microerror.Maskf(invalidConfigError, "unknown pull policy %q, must be one of %q, %q or %q", config.Pull, PullAlways, PullMissing, PullNever)
+	}
+
+	if config.ImageDigest != "" && !strings.HasPrefix(config.ImageDigest, "sha256:") {
+		return microerror.Maskf(invalidConfigError, "image digest %q must start with 'sha256:'", config.ImageDigest)
+	}
+
+	if config.ImageArchive != "" && config.Pull == PullAlways {
+		return microerror.Maskf(invalidConfigError, "an image archive can not be combined with pull policy %q", PullAlways)
+	}
+
+	return nil
+}
+
+// prepareImage loads or pulls the image according to the pull policy and
+// returns the resolved image digest.
+func (r *containerRunner) prepareImage(ctx context.Context, out io.Writer) (string, error) {
+	if r.imageArchive != "" {
+		fmt.Fprintf(out, "Loading image from %s\n", r.imageArchive)
+		_, err := r.command(ctx, "load", "--input", r.imageArchive)
+		if err != nil {
+			return "", microerror.Maskf(imageError, "loading image archive %q: %s", r.imageArchive, err)
+		}
+	}
+
+	_, inspectErr := r.command(ctx, "image", "inspect", r.image)
+
+	switch {
+	case r.pull == PullAlways, r.pull == PullMissing && inspectErr != nil && r.imageArchive == "":
+		fmt.Fprintf(out, "Pulling image %s\n", r.image)
+		_, err := r.command(ctx, "pull", r.image)
+		if err != nil {
+			return "", microerror.Maskf(imageError, "pulling image %q: %s", r.image, err)
+		}
+	case inspectErr != nil:
+		return "", microerror.Maskf(imageError, "image %q is not available locally", r.image)
+	}
+
+	repoDigests, err := r.command(ctx, "image", "inspect", "--format", "{{range .RepoDigests}}{{.}} {{end}}", r.image)
+	if err != nil {
+		return "", microerror.Maskf(imageError, "resolving digest of image %q: %s", r.image, err)
+	}
+
+	image := engine.Image{RepoDigests: strings.Fields(repoDigests)}
+	return image.Digest(r.image), nil
+}
+
+// printImage prints the image lighthouse runs in, with its registry digest
+// if it has one.
+func printImage(out io.Writer, image, digest string) {
+	if digest == "" {
+		fmt.Fprintf(out, "Using image %s, which has no registry digest\n", image)
+		return
+	}
+
+	fmt.Fprintf(out, "Using image %s (%s)\n", image, digest)
+}
+
+// command executes the container CLI with the given arguments and returns
+// its standard output.
+func (r *containerRunner) command(ctx context.Context, args ...string) (string, error) {
+	command := exec.CommandContext(ctx, r.binary, args...)
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	err := command.Run()
+	if err != nil {
+		return "", microerror.Maskf(err, "%s", strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
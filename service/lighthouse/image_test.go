@@ -0,0 +1,25 @@
+package lighthouse
+
+import "testing"
+
+// TestImageReference checks how image, tag and digest are combined.
+func TestImageReference(t *testing.T) {
+	testCases := []struct {
+		config   RunnerConfig
+		expected string
+	}{
+		{RunnerConfig{}, "quay.io/giantswarm/lighthouse:latest"},
+		{RunnerConfig{ImageTag: "4.0.0"}, "quay.io/giantswarm/lighthouse:4.0.0"},
+		{RunnerConfig{ImageTag: "4.0.0", ImageDigest: "sha256:abc"}, "quay.io/giantswarm/lighthouse@sha256:abc"},
+		{RunnerConfig{Image: "localhost:5000/lighthouse"}, "localhost:5000/lighthouse:latest"},
+		{RunnerConfig{Image: "localhost:5000/lighthouse:1.2", ImageTag: "latest"}, "localhost:5000/lighthouse:1.2"},
+		{RunnerConfig{Image: "lighthouse@sha256:def"}, "lighthouse@sha256:def"},
+	}
+
+	for _, tc := range testCases {
+		ref := imageReference(tc.config)
+		if ref != tc.expected {
+			t.Errorf("config %+v: expected %q, got %q", tc.config, tc.expected, ref)
+		}
+	}
+}
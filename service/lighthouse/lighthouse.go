@@ -2,82 +2,327 @@
 package lighthouse
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/giantswarm/microerror"
 )
 
-// AuditURL creates a lighthouse report and returns the path
-func AuditURL(url, name, formFactor string, dockerLinks []string, ignoreCertErrors bool) (path string, err error) {
-	fmt.Printf("Creating lighthouse report\nURL: %s\nForm factor: %s\nOutput file: %s.json\n", url, formFactor, name)
+// Config holds the settings for auditing a single URL.
+type Config struct {
+	// URL is the address to audit.
+	URL string
+	// Name is the output file name prefix, without the .json extension.
+	// It may contain slashes to write to subdirectories of OutputDir.
+	Name string
+	// OutputDir is the directory reports are written to. Defaults to the
+	// working directory.
+	OutputDir string
+	// OutputFormats are the report formats written as <name>.<format>.
+	// JSON is always written.
+	OutputFormats []string
+	// SaveAssets keeps the trace and the devtools log of the run as
+	// <name>.trace.json and <name>.devtoolslog.json.
+	SaveAssets bool
+	// FormFactor is either FormFactorDesktop or FormFactorMobile. Defaults
+	// to FormFactorDesktop.
+	FormFactor string
+	// Preset is the name of the emulation preset Throttling and Screen
+	// were taken from, if any.
+	Preset string
+	// Throttling overrides the lighthouse throttling defaults.
+	Throttling Throttling
+	// Screen overrides the emulated screen of the form factor.
+	Screen Screen
+	// IgnoreCertErrors makes Chrome accept invalid certificates.
+	IgnoreCertErrors bool
+	// Runs is the number of lighthouse runs to perform. If greater than
+	// one, the median run is written as the report. Defaults to 1.
+	Runs int
+	// MedianMetric is the category or audit ID whose score is used to
+	// pick the median run. Defaults to "performance".
+	MedianMetric string
+	// Output receives progress messages. Defaults to os.Stdout.
+	Output io.Writer
+	// Runner executes lighthouse.
+	Runner Runner
+	// Timeout limits the duration of each lighthouse run. Zero means no
+	// timeout.
+	Timeout time.Duration
+	// Retries is the number of times a failed or broken run is repeated.
+	Retries int
+	// RetryBackoff is the delay before the first retry. It doubles with
+	// every further retry. Defaults to DefaultRetryBackoff.
+	RetryBackoff time.Duration
+	// ConfigPath is a lighthouse config file, e.g. with custom audits.
+	ConfigPath string
+	// OnlyCategories restricts the audit to these category IDs.
+	OnlyCategories []string
+	// OnlyAudits restricts the audit to these audit IDs.
+	OnlyAudits []string
+	// SkipAudits excludes these audit IDs.
+	SkipAudits []string
+	// LoginScript is a Puppeteer script run in the same browser before
+	// the audit, e.g. to submit a login form. Storage is not reset
+	// between the login and the audit.
+	LoginScript string
+	// ExtraHeaders are sent with every request lighthouse makes. They may
+	// contain secrets and are never logged or recorded.
+	ExtraHeaders map[string]string
+	// BenchmarkRange is the range of lighthouse's benchmark index in which
+	// runs are trusted. The zero value accepts any index.
+	BenchmarkRange BenchmarkRange
+	// Thresholds are the minimum category scores of the report. Missing
+	// them fails the audit, but the report is kept.
+	Thresholds Thresholds
+}
+
+// AuditURL creates a lighthouse report and returns the path. Cancelling ctx
+// stops the running audit. If the report scores below a threshold, the path
+// is returned along with a thresholdError.
+func AuditURL(ctx context.Context, config Config) (path string, err error) {
+	if config.Runner == nil {
+		return "", microerror.Maskf(invalidConfigError, "Runner must not be empty")
+	}
+	if config.FormFactor == "" {
+		config.FormFactor = FormFactorDesktop
+	}
+	err = ValidateFormFactor(config.FormFactor)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	err = config.Throttling.Validate()
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	err = config.Screen.Validate()
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	err = config.BenchmarkRange.Validate()
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	err = config.Thresholds.Validate()
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	if config.Runs < 1 {
+		config.Runs = 1
+	}
+	if config.MedianMetric == "" {
+		config.MedianMetric = DefaultMedianMetric
+	}
+	if config.Output == nil {
+		config.Output = os.Stdout
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = DefaultRetryBackoff
+	}
+	if config.OutputDir == "" {
+		config.OutputDir = "."
+	}
+	err = ValidateFormats(config.OutputFormats)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	config.OutputFormats = outputFormats(config.OutputFormats)
+
+	base := filepath.Join(config.OutputDir, config.Name)
+	fmt.Fprintf(config.Output, "Creating lighthouse report\nURL: %s\nForm factor: %s\nOutput file: %s.json\n", config.URL, config.FormFactor, base)
+
+	err = os.MkdirAll(filepath.Dir(base), 0755)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	meta := config.Runner.Metadata()
+	meta.Settings, err = settings(config)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	if config.Runs == 1 {
+		attempts, err := runWithRetries(ctx, config, config.Name)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
 
-	pwd, err := os.Getwd()
+		path = fmt.Sprintf("%s.json", base)
+		meta.Attempts = attempts
+		meta.BenchmarkIndex, err = readBenchmarkIndex(path)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+		err = writeMetadata(path, meta)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		err = checkThresholds(config, path)
+		if err != nil {
+			return path, microerror.Mask(err)
+		}
+
+		return path, nil
+	}
+
+	runPaths := []string{}
+	runAttempts := []int{}
+	for i := 1; i <= config.Runs; i++ {
+		fmt.Fprintf(config.Output, "Run %d of %d\n", i, config.Runs)
+
+		runName := fmt.Sprintf("%s.run-%d", config.Name, i)
+		attempts, err := runWithRetries(ctx, config, runName)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		runPaths = append(runPaths, filepath.Join(config.OutputDir, runName+".json"))
+		runAttempts = append(runAttempts, attempts)
+	}
+
+	path = fmt.Sprintf("%s.json", base)
+	summary, err := writeMedianReport(runPaths, path, config.MedianMetric)
 	if err != nil {
 		return "", microerror.Mask(err)
 	}
 
-	tmpDir, err := ioutil.TempDir("/tmp", "lighthouse-temp")
+	medianName := fmt.Sprintf("%s.run-%d", config.Name, summary.MedianRun)
+	err = copyOutputs(config.OutputDir, medianName, config.Name, config.OutputFormats, config.SaveAssets)
 	if err != nil {
 		return "", microerror.Mask(err)
 	}
-	defer os.RemoveAll(tmpDir)
 
-	if formFactor != "desktop" && formFactor != "mobile" {
-		formFactor = "desktop"
+	summary.Attempts = runAttempts
+	for _, p := range runPaths {
+		index, err := readBenchmarkIndex(p)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+		summary.BenchmarkIndexes = append(summary.BenchmarkIndexes, index)
+	}
+	err = writeSummary(summary, fmt.Sprintf("%s.summary.json", base))
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	meta.Attempts = runAttempts[summary.MedianRun-1]
+	meta.BenchmarkIndex = summary.BenchmarkIndexes[summary.MedianRun-1]
+	err = writeMetadata(path, meta)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	printSummary(config.Output, summary)
+
+	err = checkThresholds(config, path)
+	if err != nil {
+		return path, microerror.Mask(err)
+	}
+
+	return path, nil
+}
+
+// runLighthouse executes one lighthouse run and writes the report to
+// <name>.json in the working directory.
+func runLighthouse(ctx context.Context, config Config, name string) error {
+	workDir, err := filepath.Abs(config.OutputDir)
+	if err != nil {
+		return microerror.Mask(err)
 	}
 
 	ignoreCertErrorsFlag := ""
-	if ignoreCertErrors {
+	if config.IgnoreCertErrors {
 		ignoreCertErrorsFlag = "--ignore-certificate-errors"
 	}
 
-	linkArgs := []string{}
-	for _, l := range dockerLinks {
-		linkArgs = append(linkArgs, fmt.Sprintf("--link=%s", l))
+	job := Job{
+		WorkDir: workDir,
+		Name:    name,
+		Args: []string{
+			"--quiet",
+			"--no-enable-error-reporting",
+			fmt.Sprintf("--chrome-flags=--no-sandbox --headless %s", ignoreCertErrorsFlag),
+			fmt.Sprintf("--output-path=%s.json", name),
+		},
+		Output: config.Output,
+	}
+
+	job.Args = append(job.Args, outputArgs(config.OutputFormats, config.SaveAssets)...)
+	job.Args = append(job.Args, formFactorArgs(config.FormFactor, config.Screen)...)
+	job.Args = append(job.Args, config.Throttling.args()...)
+
+	if config.ConfigPath != "" {
+		configPath, err := filepath.Abs(config.ConfigPath)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		// mount the whole directory, the config may refer to custom
+		// audits next to it
+		job.Mounts = append(job.Mounts, filepath.Dir(configPath))
+		job.Args = append(job.Args, fmt.Sprintf("--config-path=%s", configPath))
+	}
+	if len(config.OnlyCategories) > 0 {
+		job.Args = append(job.Args, fmt.Sprintf("--only-categories=%s", strings.Join(config.OnlyCategories, ",")))
+	}
+	if len(config.OnlyAudits) > 0 {
+		job.Args = append(job.Args, fmt.Sprintf("--only-audits=%s", strings.Join(config.OnlyAudits, ",")))
+	}
+	if len(config.SkipAudits) > 0 {
+		job.Args = append(job.Args, fmt.Sprintf("--skip-audits=%s", strings.Join(config.SkipAudits, ",")))
+	}
+
+	if len(config.ExtraHeaders) > 0 {
+		dir, path, err := writeExtraHeaders(config.ExtraHeaders)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		defer os.RemoveAll(dir)
+
+		job.SecretMounts = append(job.SecretMounts, dir)
+		job.Args = append(job.Args, fmt.Sprintf("--extra-headers=%s", path))
 	}
 
-	args := []string{
-		"run",
-		"--rm",
-		"--tty",
-		fmt.Sprintf("-v=%s:/workdir", pwd),
-		fmt.Sprintf("-v=%s:/dev/shm", tmpDir),
-		"-w=/workdir",
+	job.Args = append(job.Args, config.URL)
+
+	if config.LoginScript != "" {
+		job, err = withLoginScript(job, config.LoginScript, name)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
 	}
 
-	moreArgs := []string{
-		"quay.io/giantswarm/lighthouse:latest",
-		"lighthouse",
-		"--quiet",
-		"--no-enable-error-reporting",
-		"--output=json",
-		fmt.Sprintf("--chrome-flags=--no-sandbox --headless %s", ignoreCertErrorsFlag),
-		fmt.Sprintf("--emulated-form-factor=%s", formFactor),
-		fmt.Sprintf("--output-path=/workdir/%s.json", name),
-		url,
+	err = config.Runner.Run(ctx, job)
+	if IsTimeoutError(err) {
+		return microerror.Maskf(err, "auditing %s timed out after %s", config.URL, config.Timeout)
 	}
 
-	for _, a := range linkArgs {
-		args = append(args, a)
+	if config.LoginScript != "" {
+		loginErr := loginError(workDir, config.LoginScript, name)
+		if loginErr != nil {
+			return microerror.Mask(loginErr)
+		}
 	}
-	for _, a := range moreArgs {
-		args = append(args, a)
+	if err != nil {
+		return microerror.Mask(err)
 	}
 
-	command := exec.Command("docker", args...)
-	var stdout, stderr bytes.Buffer
-	command.Stdout = &stdout
-	command.Stderr = &stderr
-	err = command.Run()
+	err = collectOutputs(workDir, name, config.OutputFormats, config.SaveAssets)
 	if err != nil {
-		_, errStr := string(stdout.Bytes()), string(stderr.Bytes())
-		fmt.Printf("%s\n", errStr)
-		return "", microerror.Mask(fmt.Errorf("cmd.Run() failed with %s", err))
+		return microerror.Mask(err)
 	}
 
-	return fmt.Sprintf("%s.json", name), nil
+	return nil
 }
@@ -0,0 +1,99 @@
+package lighthouse
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/giantswarm/microerror"
+)
+
+// loginDriverScript launches Chrome, runs the login script given as first
+// argument against it with Puppeteer and then runs lighthouse with the
+// remaining arguments in the same browser, keeping its storage. If the
+// login script fails, its error is written to the file given as second
+// argument, to tell it apart from lighthouse failures.
+//
+// The login script must export an async function taking the Puppeteer
+// browser and an object with the audited url.
+const loginDriverScript = `
+const {execSync, spawnSync} = require('child_process');
+const fs = require('fs');
+const path = require('path');
+const [script, errorPath, ...args] = process.argv.slice(1);
+const url = args.pop();
+
+const roots = [];
+try { roots.push(execSync('npm root -g').toString().trim()); } catch (err) {}
+const load = (...names) => {
+  const paths = roots.concat(roots.map((root) => path.join(root, 'lighthouse', 'node_modules')));
+  for (const name of names) {
+    try { return require(require.resolve(name, {paths})); } catch (err) {}
+  }
+  throw new Error('could not find ' + names.join(' or '));
+};
+
+const chromeFlags = (args.find((a) => a.startsWith('--chrome-flags=')) || '--chrome-flags=')
+  .slice('--chrome-flags='.length).split(' ').filter(Boolean);
+
+(async () => {
+  const chrome = await load('chrome-launcher').launch({chromeFlags});
+  try {
+    try {
+      const puppeteer = load('puppeteer-core', 'puppeteer');
+      const browser = await puppeteer.connect({browserURL: 'http://127.0.0.1:' + chrome.port});
+      await require(path.resolve(script))(browser, {url});
+      browser.disconnect();
+    } catch (err) {
+      fs.writeFileSync(errorPath, String((err && err.stack) || err));
+      process.exitCode = 1;
+      return;
+    }
+    const lighthouse = spawnSync('lighthouse', args.concat(['--port=' + chrome.port, '--disable-storage-reset', url]), {stdio: 'inherit'});
+    process.exitCode = lighthouse.status === null ? 1 : lighthouse.status;
+  } finally {
+    await chrome.kill();
+  }
+})().catch((err) => { process.stderr.write(String((err && err.stack) || err)); process.exit(1); });
+`
+
+// loginErrorPath returns the path the login driver writes login script
+// errors to, relative to the work directory.
+func loginErrorPath(name string) string {
+	return fmt.Sprintf("%s.login-error.txt", name)
+}
+
+// withLoginScript changes the job to run lighthouse through the login
+// driver.
+func withLoginScript(job Job, script, name string) (Job, error) {
+	script, err := filepath.Abs(script)
+	if err != nil {
+		return Job{}, microerror.Mask(err)
+	}
+
+	// a record left over from an interrupted run must not be mistaken
+	// for a failure of this one
+	os.Remove(filepath.Join(job.WorkDir, loginErrorPath(name)))
+
+	job.Command = "node"
+	job.Mounts = append(job.Mounts, filepath.Dir(script))
+	job.Args = append([]string{"-e", loginDriverScript, script, loginErrorPath(name)}, job.Args...)
+
+	return job, nil
+}
+
+// loginError returns a loginScriptError if the login driver recorded a
+// failure of the login script, and removes the record.
+func loginError(workDir, script, name string) error {
+	path := filepath.Join(workDir, loginErrorPath(name))
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+	os.Remove(path)
+
+	return microerror.Maskf(loginScriptError, "login script %s failed: %s", script, data)
+}
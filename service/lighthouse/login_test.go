@@ -0,0 +1,63 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAuditURLLoginScript checks that lighthouse is run through the login
+// driver and that login script failures are told apart from lighthouse
+// failures.
+func TestAuditURLLoginScript(t *testing.T) {
+	runner := newFakeRunner(t)
+
+	defer inTempDir(t)()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{
+		URL:         "https://example.com/account",
+		Name:        "example",
+		LoginScript: "login.js",
+		Runner:      runner,
+		Output:      &bytes.Buffer{},
+	}
+
+	_, err = AuditURL(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := runner.jobs[0]
+	if job.Command != "node" || job.Args[0] != "-e" {
+		t.Errorf("expected lighthouse to run through the login driver, got %s %v", job.Command, job.Args[:1])
+	}
+	if job.Args[2] != filepath.Join(pwd, "login.js") {
+		t.Errorf("expected absolute login script path, got %q", job.Args[2])
+	}
+	if job.Args[len(job.Args)-1] != config.URL {
+		t.Errorf("expected url as last argument, got %q", job.Args[len(job.Args)-1])
+	}
+	if len(job.Mounts) != 1 || job.Mounts[0] != pwd {
+		t.Errorf("expected login script directory to be mounted, got %v", job.Mounts)
+	}
+
+	config.URL = "https://example.com/login-fail"
+	_, err = AuditURL(context.Background(), config)
+	if !IsLoginScriptError(err) {
+		t.Fatalf("expected login script error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "fake login failure") {
+		t.Errorf("expected login script output in error, got %q", err.Error())
+	}
+	if _, err := os.Stat(loginErrorPath("example")); !os.IsNotExist(err) {
+		t.Errorf("expected login error record to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package lighthouse
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// hostAlias is the host name containers use to reach the host if they are
+// not in the host network. Podman provides podmanHostAlias itself.
+const (
+	hostAlias       = "host.docker.internal"
+	podmanHostAlias = "host.containers.internal"
+)
+
+// ReachLoopback adapts the runner config and URLs so that URLs pointing to
+// the loopback interface, like http://localhost:3000/, reach the host
+// instead of the lighthouse container itself. On Linux the container is
+// moved to the host network, elsewhere or if a network is configured
+// already, loopback hosts are replaced with host.docker.internal, or
+// host.containers.internal with podman. The URLs may also be the host:port
+// addresses --wait-for accepts. It returns the adapted config and URLs and
+// a description of each change made.
+func ReachLoopback(config RunnerConfig, urls []string, goos string) (RunnerConfig, []string, []string) {
+	if config.Runtime == RuntimeNative || config.Network == NetworkHost {
+		return config, urls, nil
+	}
+
+	loopback := false
+	for _, u := range urls {
+		if isLoopbackURL(u) {
+			loopback = true
+			break
+		}
+	}
+	if !loopback {
+		return config, urls, nil
+	}
+
+	changes := []string{}
+
+	if goos == "linux" && config.Network == "" && len(config.DockerLinks) == 0 {
+		config.Network = NetworkHost
+		changes = append(changes, "Using the host network for the lighthouse container to reach localhost URLs")
+		return config, urls, changes
+	}
+
+	alias := hostAlias
+	if config.Runtime == RuntimePodman {
+		alias = podmanHostAlias
+	}
+
+	rewritten := make([]string, len(urls))
+	for i, u := range urls {
+		rewritten[i] = u
+		if isLoopbackURL(u) {
+			rewritten[i] = replaceHost(u, alias)
+			changes = append(changes, fmt.Sprintf("Auditing %s as %s", u, rewritten[i]))
+		}
+	}
+
+	// podman adds host.containers.internal to every container
+	if config.Runtime == RuntimePodman {
+		return config, rewritten, changes
+	}
+
+	hostEntry := hostAlias + ":" + hostGateway
+	found := false
+	for _, h := range config.ExtraHosts {
+		if strings.HasPrefix(h, hostAlias+":") {
+			found = true
+		}
+	}
+	if !found {
+		config.ExtraHosts = append(append([]string{}, config.ExtraHosts...), hostEntry)
+		changes = append(changes, fmt.Sprintf("Adding host %s to the lighthouse container", hostEntry))
+	}
+
+	return config, rewritten, changes
+}
+
+// LoopbackReachable returns whether lighthouse can reach servers that only
+// listen on the host's loopback interface, either directly or after
+// ReachLoopback moved the container to the host network. Otherwise such
+// servers have to listen on the interface the host alias maps to.
+func LoopbackReachable(config RunnerConfig, goos string) bool {
+	if config.Runtime == RuntimeNative || config.Network == NetworkHost {
+		return true
+	}
+
+	return goos == "linux" && config.Network == "" && len(config.DockerLinks) == 0
+}
+
+// parseTarget parses URLs as well as host:port addresses without a scheme.
+// It returns whether the scheme was missing.
+func parseTarget(target string) (*url.URL, bool, error) {
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		return u, false, err
+	}
+
+	u, err := url.Parse("//" + target)
+	return u, true, err
+}
+
+// isLoopbackURL returns true if the host of the URL or host:port address
+// refers to the loopback interface.
+func isLoopbackURL(rawURL string) bool {
+	u, _, err := parseTarget(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// replaceHost replaces the host of the URL or host:port address, keeping
+// the port.
+func replaceHost(rawURL, host string) string {
+	u, schemeless, err := parseTarget(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else {
+		u.Host = host
+	}
+
+	if schemeless {
+		return strings.TrimPrefix(u.String(), "//")
+	}
+
+	return u.String()
+}
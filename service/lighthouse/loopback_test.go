@@ -0,0 +1,125 @@
+package lighthouse
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestReachLoopback checks how localhost URLs are made reachable from the
+// container.
+func TestReachLoopback(t *testing.T) {
+	testCases := []struct {
+		name           string
+		config         RunnerConfig
+		urls           []string
+		goos           string
+		expectedConfig RunnerConfig
+		expectedURLs   []string
+	}{
+		{
+			name:           "no loopback",
+			config:         RunnerConfig{},
+			urls:           []string{"https://example.com/"},
+			goos:           "linux",
+			expectedConfig: RunnerConfig{},
+			expectedURLs:   []string{"https://example.com/"},
+		},
+		{
+			name:           "linux uses host network",
+			config:         RunnerConfig{},
+			urls:           []string{"http://localhost:3000/"},
+			goos:           "linux",
+			expectedConfig: RunnerConfig{Network: NetworkHost},
+			expectedURLs:   []string{"http://localhost:3000/"},
+		},
+		{
+			name:           "darwin rewrites host",
+			config:         RunnerConfig{},
+			urls:           []string{"http://127.0.0.1:3000/a?b=c", "https://example.com/"},
+			goos:           "darwin",
+			expectedConfig: RunnerConfig{ExtraHosts: []string{"host.docker.internal:host-gateway"}},
+			expectedURLs:   []string{"http://host.docker.internal:3000/a?b=c", "https://example.com/"},
+		},
+		{
+			name:           "linux with network rewrites host",
+			config:         RunnerConfig{Network: "ci_default"},
+			urls:           []string{"http://[::1]/"},
+			goos:           "linux",
+			expectedConfig: RunnerConfig{Network: "ci_default", ExtraHosts: []string{"host.docker.internal:host-gateway"}},
+			expectedURLs:   []string{"http://host.docker.internal/"},
+		},
+		{
+			name:           "wait target without scheme",
+			config:         RunnerConfig{},
+			urls:           []string{"localhost:3000", "tcp://127.0.0.1:5432", "db:5432"},
+			goos:           "darwin",
+			expectedConfig: RunnerConfig{ExtraHosts: []string{"host.docker.internal:host-gateway"}},
+			expectedURLs:   []string{"host.docker.internal:3000", "tcp://host.docker.internal:5432", "db:5432"},
+		},
+		{
+			name:           "podman uses its own alias",
+			config:         RunnerConfig{Runtime: RuntimePodman},
+			urls:           []string{"http://localhost:3000/", "localhost:3000"},
+			goos:           "darwin",
+			expectedConfig: RunnerConfig{Runtime: RuntimePodman},
+			expectedURLs:   []string{"http://host.containers.internal:3000/", "host.containers.internal:3000"},
+		},
+		{
+			name:           "linux uses host network for wait target",
+			config:         RunnerConfig{},
+			urls:           []string{"localhost:3000", "https://example.com/"},
+			goos:           "linux",
+			expectedConfig: RunnerConfig{Network: NetworkHost},
+			expectedURLs:   []string{"localhost:3000", "https://example.com/"},
+		},
+		{
+			name:           "host network is left alone",
+			config:         RunnerConfig{Network: NetworkHost},
+			urls:           []string{"http://localhost/"},
+			goos:           "darwin",
+			expectedConfig: RunnerConfig{Network: NetworkHost},
+			expectedURLs:   []string{"http://localhost/"},
+		},
+		{
+			name:           "native is left alone",
+			config:         RunnerConfig{Runtime: RuntimeNative},
+			urls:           []string{"http://localhost/"},
+			goos:           "linux",
+			expectedConfig: RunnerConfig{Runtime: RuntimeNative},
+			expectedURLs:   []string{"http://localhost/"},
+		},
+	}
+
+	for _, tc := range testCases {
+		config, urls, _ := ReachLoopback(tc.config, tc.urls, tc.goos)
+		if !reflect.DeepEqual(config, tc.expectedConfig) {
+			t.Errorf("%s: expected config %+v, got %+v", tc.name, tc.expectedConfig, config)
+		}
+		if !reflect.DeepEqual(urls, tc.expectedURLs) {
+			t.Errorf("%s: expected URLs %v, got %v", tc.name, tc.expectedURLs, urls)
+		}
+	}
+}
+
+// TestLoopbackReachable checks when servers listening on the host's
+// loopback interface are reachable.
+func TestLoopbackReachable(t *testing.T) {
+	testCases := []struct {
+		config   RunnerConfig
+		goos     string
+		expected bool
+	}{
+		{config: RunnerConfig{}, goos: "linux", expected: true},
+		{config: RunnerConfig{}, goos: "darwin", expected: false},
+		{config: RunnerConfig{Network: "mynet"}, goos: "linux", expected: false},
+		{config: RunnerConfig{DockerLinks: []string{"site:site"}}, goos: "linux", expected: false},
+		{config: RunnerConfig{Network: NetworkHost}, goos: "darwin", expected: true},
+		{config: RunnerConfig{Runtime: RuntimeNative}, goos: "darwin", expected: true},
+	}
+
+	for _, tc := range testCases {
+		if reachable := LoopbackReachable(tc.config, tc.goos); reachable != tc.expected {
+			t.Errorf("%+v on %s: expected %t, got %t", tc.config, tc.goos, tc.expected, reachable)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package lighthouse
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Metadata describes how a report was created. It is written next to each
+// report as <name>.meta.json.
+type Metadata struct {
+	// Runtime is the runtime lighthouse was executed with.
+	Runtime string `json:"runtime"`
+	// Image is the image reference used by container runtimes.
+	Image string `json:"image,omitempty"`
+	// ImageDigest is the resolved digest of Image.
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// Attempts is the number of lighthouse runs it took to create the
+	// report, including retries.
+	Attempts int `json:"attempts"`
+	// Resources are the resource limits of the lighthouse container.
+	Resources *Resources `json:"resources,omitempty"`
+	// BenchmarkIndex is lighthouse's measure of the CPU speed available to
+	// Chrome during the run. Scores of runs with very different indexes
+	// are not comparable.
+	BenchmarkIndex float64 `json:"benchmarkIndex,omitempty"`
+	// Settings are the lighthouse settings the report was created with.
+	Settings Settings `json:"settings"`
+}
+
+// MetadataPath returns the path of the metadata file belonging to the given
+// report path.
+func MetadataPath(reportPath string) string {
+	return strings.TrimSuffix(reportPath, ".json") + ".meta.json"
+}
+
+// ReadMetadata reads the metadata file belonging to the given report path.
+func ReadMetadata(reportPath string) (*Metadata, error) {
+	data, err := ioutil.ReadFile(MetadataPath(reportPath))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var meta Metadata
+	err = json.Unmarshal(data, &meta)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return &meta, nil
+}
+
+func writeMetadata(reportPath string, meta Metadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = ioutil.WriteFile(MetadataPath(reportPath), data, 0644)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/giantswarm/microerror"
+)
+
+// nativeRunner runs a lighthouse binary installed on the host.
+type nativeRunner struct {
+	binary string
+}
+
+func newNativeRunner() (*nativeRunner, error) {
+	binary, err := exec.LookPath("lighthouse")
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "could not find a lighthouse binary in PATH")
+	}
+
+	return &nativeRunner{binary: binary}, nil
+}
+
+// Prepare does nothing for the native runtime.
+func (r *nativeRunner) Prepare(ctx context.Context, out io.Writer) error {
+	return nil
+}
+
+// Metadata returns the runtime.
+func (r *nativeRunner) Metadata() Metadata {
+	return Metadata{Runtime: RuntimeNative}
+}
+
+// Exec runs a command on the host and returns its output.
+func (r *nativeRunner) Exec(ctx context.Context, args []string) ([]byte, error) {
+	command := exec.CommandContext(ctx, args[0], args[1:]...)
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	err := command.Run()
+	if ctx.Err() != nil {
+		return nil, contextError(ctx)
+	}
+	if err != nil {
+		return nil, microerror.Maskf(runFailedError, "%s failed with %s: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// Run executes lighthouse in the job's work directory. The process is killed
+// if ctx is done before it finishes.
+func (r *nativeRunner) Run(ctx context.Context, job Job) error {
+	binary := r.binary
+	if job.Command != "" {
+		binary = job.Command
+	}
+
+	command := exec.CommandContext(ctx, binary, job.Args...)
+	command.Dir = job.WorkDir
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	err := command.Run()
+	if ctx.Err() != nil {
+		return contextError(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(job.Output, "%s\n", stderr.String())
+		return microerror.Maskf(runFailedError, "%s failed with %s", binary, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package lighthouse
+
+import (
+	"net"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// NetworkHost makes the lighthouse container use the host's network.
+const NetworkHost = "host"
+
+// hostGateway is the special --add-host address Docker resolves to the
+// host.
+const hostGateway = "host-gateway"
+
+func validateNetworkConfig(config RunnerConfig) error {
+	if config.Network != "" && len(config.DockerLinks) > 0 {
+		if config.Network == NetworkHost {
+			return microerror.Maskf(invalidConfigError, "docker links can not be used with the host network")
+		}
+		return microerror.Maskf(invalidConfigError, "docker links can not be combined with network %q, containers in a user-defined network are reachable by name", config.Network)
+	}
+
+	for _, h := range config.ExtraHosts {
+		err := validateExtraHost(h)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// validateExtraHost checks an --add-host value of the form host:ip.
+func validateExtraHost(value string) error {
+	i := strings.Index(value, ":")
+	if i < 1 {
+		return microerror.Maskf(invalidConfigError, "extra host %q must have the form host:ip", value)
+	}
+
+	ip := value[i+1:]
+	if ip != hostGateway && net.ParseIP(ip) == nil {
+		return microerror.Maskf(invalidConfigError, "extra host %q has an invalid IP address", value)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package lighthouse
+
+import "testing"
+
+// TestValidateNetworkConfig checks conflicting network settings.
+func TestValidateNetworkConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config RunnerConfig
+		valid  bool
+	}{
+		{"links", RunnerConfig{DockerLinks: []string{"site:site"}}, true},
+		{"network", RunnerConfig{Network: "ci_default"}, true},
+		{"host network", RunnerConfig{Network: NetworkHost, ExtraHosts: []string{"site.local:127.0.0.1"}}, true},
+		{"links and network", RunnerConfig{Network: "ci_default", DockerLinks: []string{"site:site"}}, false},
+		{"links and host network", RunnerConfig{Network: NetworkHost, DockerLinks: []string{"site:site"}}, false},
+		{"host gateway", RunnerConfig{ExtraHosts: []string{"host.docker.internal:host-gateway"}}, true},
+		{"ipv6", RunnerConfig{ExtraHosts: []string{"site:::1"}}, true},
+		{"no ip", RunnerConfig{ExtraHosts: []string{"site"}}, false},
+		{"invalid ip", RunnerConfig{ExtraHosts: []string{"site:localhost"}}, false},
+	}
+
+	for _, tc := range testCases {
+		err := validateNetworkConfig(tc.config)
+		if tc.valid && err != nil {
+			t.Errorf("%s: unexpected error %s", tc.name, err)
+		}
+		if !tc.valid && !IsInvalidConfigError(err) {
+			t.Errorf("%s: expected invalidConfigError, got %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package lighthouse
+
+import (
+	"os"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// FormFactorName returns the name of the report for one form factor, when a
+// URL is audited with several form factors under the same name.
+func FormFactorName(name, formFactor string) string {
+	return name + "-" + formFactor
+}
+
+// ReportPair are two reports to compare.
+type ReportPair struct {
+	// FormFactor is the form factor both reports were created with, or
+	// empty if the reports were given directly.
+	FormFactor string
+	A          string
+	B          string
+}
+
+// PairReports returns the reports to compare. a and b are either report
+// files, or names of reports created with several form factors, with or
+// without the .json extension. Their <name>-desktop.json and
+// <name>-mobile.json reports are paired by form factor. Form factors with a
+// report on only one side are returned as missing.
+func PairReports(a, b string) ([]ReportPair, []string, error) {
+	if isFile(a) && isFile(b) {
+		return []ReportPair{{A: a, B: b}}, nil, nil
+	}
+
+	pairs := []ReportPair{}
+	missing := []string{}
+	for _, f := range []string{FormFactorDesktop, FormFactorMobile} {
+		pair := ReportPair{
+			FormFactor: f,
+			A:          FormFactorName(strings.TrimSuffix(a, ".json"), f) + ".json",
+			B:          FormFactorName(strings.TrimSuffix(b, ".json"), f) + ".json",
+		}
+
+		foundA, foundB := isFile(pair.A), isFile(pair.B)
+		if foundA && foundB {
+			pairs = append(pairs, pair)
+		} else if foundA || foundB {
+			missing = append(missing, f)
+		}
+	}
+
+	if len(pairs) == 0 {
+		return nil, nil, microerror.Maskf(invalidConfigError, "found neither the reports %q and %q nor reports of both for the same form factor", a, b)
+	}
+
+	return pairs, missing, nil
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
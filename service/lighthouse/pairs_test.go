@@ -0,0 +1,63 @@
+package lighthouse
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// TestPairReports checks pairing reports given directly or by the name of
+// reports per form factor.
+func TestPairReports(t *testing.T) {
+	defer inTempDir(t)()
+
+	for _, name := range []string{"a.json", "b.json", "before-desktop.json", "before-mobile.json", "after-desktop.json", "after-mobile.json", "partial-desktop.json", "20190314-150926-1-desktop.json", "20190314-150926-1-mobile.json"} {
+		err := ioutil.WriteFile(name, []byte("{}"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		a, b            string
+		expectedPairs   []ReportPair
+		expectedMissing []string
+		valid           bool
+	}{
+		{"a.json", "b.json", []ReportPair{{A: "a.json", B: "b.json"}}, nil, true},
+		{"before", "after.json", []ReportPair{
+			{FormFactor: FormFactorDesktop, A: "before-desktop.json", B: "after-desktop.json"},
+			{FormFactor: FormFactorMobile, A: "before-mobile.json", B: "after-mobile.json"},
+		}, nil, true},
+		{"partial", "after", []ReportPair{
+			{FormFactor: FormFactorDesktop, A: "partial-desktop.json", B: "after-desktop.json"},
+		}, []string{FormFactorMobile}, true},
+		// automatic names of audits with both form factors
+		{"20190314-150926-1", "after", []ReportPair{
+			{FormFactor: FormFactorDesktop, A: "20190314-150926-1-desktop.json", B: "after-desktop.json"},
+			{FormFactor: FormFactorMobile, A: "20190314-150926-1-mobile.json", B: "after-mobile.json"},
+		}, nil, true},
+		{"a.json", "after", nil, nil, false},
+		{"missing", "other", nil, nil, false},
+	}
+
+	for _, tc := range testCases {
+		pairs, missing, err := PairReports(tc.a, tc.b)
+		if !tc.valid {
+			if !IsInvalidConfigError(err) {
+				t.Errorf("%q and %q: expected invalid config error, got %v", tc.a, tc.b, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q and %q: unexpected error %s", tc.a, tc.b, err)
+			continue
+		}
+		if len(missing) == 0 {
+			missing = nil
+		}
+		if !reflect.DeepEqual(pairs, tc.expectedPairs) || !reflect.DeepEqual(missing, tc.expectedMissing) {
+			t.Errorf("%q and %q: expected %+v missing %q, got %+v missing %q", tc.a, tc.b, tc.expectedPairs, tc.expectedMissing, pairs, missing)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Result is the outcome of auditing one URL.
+type Result struct {
+	Config Config
+	// Path is the report file path, if a report was written. It is also
+	// set if the report failed a threshold.
+	Path string
+	// Err is set if the audit failed.
+	Err error
+}
+
+// AuditURLs audits all given URLs, running up to concurrency audits at the
+// same time. A failing audit does not stop the others. Progress output of
+// each audit is written to out as one block, in the order of configs.
+// Results are returned in the order of configs, too. Once ctx is done, no
+// further audits are started.
+func AuditURLs(ctx context.Context, configs []Config, concurrency int, out io.Writer) []Result {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]Result, len(configs))
+	outputs := make([]bytes.Buffer, len(configs))
+	done := make([]chan struct{}, len(configs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				config := configs[i]
+				// a single worker can stream its output directly
+				if concurrency == 1 {
+					config.Output = out
+				} else {
+					config.Output = &outputs[i]
+				}
+
+				if ctx.Err() != nil {
+					results[i] = Result{Config: configs[i], Err: microerror.Mask(ctx.Err())}
+					close(done[i])
+					continue
+				}
+
+				path, err := AuditURL(ctx, config)
+				results[i] = Result{Config: configs[i], Path: path, Err: err}
+				close(done[i])
+			}
+		}()
+	}
+
+	go func() {
+		for i := range configs {
+			indexes <- i
+		}
+		close(indexes)
+	}()
+
+	for i := range configs {
+		<-done[i]
+		if concurrency > 1 {
+			outputs[i].WriteTo(out)
+		}
+	}
+
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,95 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+)
+
+// TestAuditURLs checks that a failing URL does not stop the others, and
+// that results and output blocks are in the order of the configs for any
+// number of workers.
+func TestAuditURLs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		concurrency int
+		cancelled   bool
+	}{
+		{name: "sequential", concurrency: 1},
+		{name: "invalid concurrency is sequential", concurrency: 0},
+		{name: "parallel", concurrency: 2},
+		{name: "more workers than URLs", concurrency: 5},
+		{name: "cancelled", concurrency: 2, cancelled: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := newFakeRunner(t)
+
+			defer inTempDir(t)()
+
+			configs := []Config{
+				{URL: "https://example.com/one", Name: "one", Runner: runner},
+				{URL: "https://example.com/fail", Name: "two", Runner: runner},
+				{URL: "https://example.com/three", Name: "three", Runner: runner},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancelled {
+				cancel()
+			}
+
+			var out bytes.Buffer
+			results := AuditURLs(ctx, configs, tc.concurrency, &out)
+
+			if len(results) != len(configs) {
+				t.Fatalf("expected %d results, got %d", len(configs), len(results))
+			}
+			for i, r := range results {
+				if r.Config.URL != configs[i].URL {
+					t.Errorf("expected result %d for %s, got %s", i, configs[i].URL, r.Config.URL)
+				}
+			}
+
+			if tc.cancelled {
+				for _, r := range results {
+					if microerror.Cause(r.Err) != context.Canceled {
+						t.Errorf("expected %s not to be audited, got %+v", r.Config.URL, r)
+					}
+				}
+				if len(runner.jobs) != 0 {
+					t.Errorf("expected no runs, got %d", len(runner.jobs))
+				}
+				return
+			}
+
+			if results[0].Err != nil || results[0].Path != "one.json" {
+				t.Errorf("unexpected result %+v", results[0])
+			}
+			if !IsRunFailedError(results[1].Err) {
+				t.Errorf("expected runFailedError, got %v", results[1].Err)
+			}
+			if results[2].Err != nil || results[2].Path != "three.json" {
+				t.Errorf("unexpected result %+v", results[2])
+			}
+
+			// each block starts with the report announcement and only
+			// mentions its own URL
+			blocks := strings.Split(out.String(), "Creating lighthouse report\n")[1:]
+			if len(blocks) != len(configs) {
+				t.Fatalf("expected %d output blocks, got:\n%s", len(configs), out.String())
+			}
+			for i, block := range blocks {
+				for j, c := range configs {
+					if strings.Contains(block, c.URL) != (i == j) {
+						t.Errorf("expected block %d to mention only %s, got:\n%s", i, configs[i].URL, block)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package lighthouse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// minMemory is the smallest memory limit Docker accepts.
+const minMemory = 6 * 1024 * 1024
+
+// Resources limit the resources of the lighthouse container, to make scores
+// less dependent on other load on the host.
+type Resources struct {
+	// CPUs is the number of CPUs the container may use, e.g. 1.5. Zero
+	// means no limit.
+	CPUs float64 `json:"cpus,omitempty"`
+	// Memory is the memory limit in bytes. Zero means no limit.
+	Memory int64 `json:"memory,omitempty"`
+	// CPUSet are the CPUs the container is pinned to, e.g. "0-1" or
+	// "0,2".
+	CPUSet string `json:"cpusetCpus,omitempty"`
+	// ShmSize is the size of /dev/shm in bytes. If zero, a host directory
+	// is mounted as /dev/shm instead, which is only limited by the disk.
+	ShmSize int64 `json:"shmSize,omitempty"`
+}
+
+var cpuSetRegexp = regexp.MustCompile(`^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$`)
+
+// Validate checks that the limits are valid.
+func (r Resources) Validate() error {
+	if r.CPUs < 0 {
+		return microerror.Maskf(invalidConfigError, "cpus must not be negative")
+	}
+	if r.Memory < 0 || r.Memory > 0 && r.Memory < minMemory {
+		return microerror.Maskf(invalidConfigError, "memory must be at least 6m")
+	}
+	if r.CPUSet != "" && !cpuSetRegexp.MatchString(r.CPUSet) {
+		return microerror.Maskf(invalidConfigError, "cpuset %q must list CPUs like 0-3 or 0,2", r.CPUSet)
+	}
+	if r.ShmSize < 0 {
+		return microerror.Maskf(invalidConfigError, "shm size must not be negative")
+	}
+
+	return nil
+}
+
+// IsZero tells whether no limit is set.
+func (r Resources) IsZero() bool {
+	return r == Resources{}
+}
+
+// String describes the limits by their docker run flags.
+func (r Resources) String() string {
+	if r.IsZero() {
+		return "no limits"
+	}
+
+	return strings.Join(r.args(), " ")
+}
+
+// args returns the docker run flags setting the limits.
+func (r Resources) args() []string {
+	args := []string{}
+	if r.CPUs > 0 {
+		args = append(args, fmt.Sprintf("--cpus=%s", strconv.FormatFloat(r.CPUs, 'f', -1, 64)))
+	}
+	if r.Memory > 0 {
+		args = append(args, fmt.Sprintf("--memory=%d", r.Memory))
+	}
+	if r.CPUSet != "" {
+		args = append(args, fmt.Sprintf("--cpuset-cpus=%s", r.CPUSet))
+	}
+	if r.ShmSize > 0 {
+		args = append(args, fmt.Sprintf("--shm-size=%d", r.ShmSize))
+	}
+
+	return args
+}
+
+var sizeRegexp = regexp.MustCompile(`^([0-9]+)([bkmg]?)b?$`)
+
+// ParseSize parses a size in bytes with an optional unit like 512m or 2g,
+// as accepted by docker run. Units are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	match := sizeRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if match == nil {
+		return 0, microerror.Maskf(invalidConfigError, "size %q must be a number with an optional unit b, k, m or g", s)
+	}
+
+	size, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, microerror.Maskf(invalidConfigError, "size %q: %s", s, err)
+	}
+
+	switch match[2] {
+	case "k":
+		size *= 1024
+	case "m":
+		size *= 1024 * 1024
+	case "g":
+		size *= 1024 * 1024 * 1024
+	}
+
+	return size, nil
+}
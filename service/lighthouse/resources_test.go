@@ -0,0 +1,66 @@
+package lighthouse
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseSize checks parsing sizes with units.
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		s        string
+		expected int64
+		valid    bool
+	}{
+		{"1024", 1024, true},
+		{"512k", 512 * 1024, true},
+		{"512m", 512 * 1024 * 1024, true},
+		{"2G", 2 * 1024 * 1024 * 1024, true},
+		{"1gb", 1024 * 1024 * 1024, true},
+		{"1.5g", 0, false},
+		{"2t", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tc := range testCases {
+		size, err := ParseSize(tc.s)
+		if tc.valid && (err != nil || size != tc.expected) {
+			t.Errorf("%q: expected %d, got %d and %v", tc.s, tc.expected, size, err)
+		} else if !tc.valid && !IsInvalidConfigError(err) {
+			t.Errorf("%q: expected invalid config error, got %v", tc.s, err)
+		}
+	}
+}
+
+// TestResources checks validating limits and the resulting docker run
+// flags.
+func TestResources(t *testing.T) {
+	testCases := []struct {
+		r            Resources
+		valid        bool
+		expectedArgs []string
+	}{
+		{Resources{}, true, []string{}},
+		{Resources{CPUs: 1.5, Memory: 2 << 30, CPUSet: "0-1", ShmSize: 1 << 30}, true, []string{"--cpus=1.5", "--memory=2147483648", "--cpuset-cpus=0-1", "--shm-size=1073741824"}},
+		{Resources{CPUSet: "0,2,4-7"}, true, []string{"--cpuset-cpus=0,2,4-7"}},
+		{Resources{CPUSet: "0-"}, false, nil},
+		{Resources{CPUs: -1}, false, nil},
+		{Resources{Memory: 1024}, false, nil},
+	}
+
+	for _, tc := range testCases {
+		err := tc.r.Validate()
+		if !tc.valid {
+			if !IsInvalidConfigError(err) {
+				t.Errorf("%+v: expected invalid config error, got %v", tc.r, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: unexpected error %s", tc.r, err)
+		}
+		if args := tc.r.args(); !reflect.DeepEqual(args, tc.expectedArgs) {
+			t.Errorf("%+v: expected %q, got %q", tc.r, tc.expectedArgs, args)
+		}
+	}
+}
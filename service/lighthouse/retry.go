@@ -0,0 +1,152 @@
+package lighthouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/lighthouse-keeper/service/parser"
+)
+
+// DefaultRetryBackoff is the delay before the first retry of a failed run.
+const DefaultRetryBackoff = 5 * time.Second
+
+// runWithRetries runs lighthouse until it writes a usable report to
+// <name>.json or the configured retries are used up. It returns the number
+// of attempts made.
+func runWithRetries(ctx context.Context, config Config, name string) (int, error) {
+	backoff := config.RetryBackoff
+	attempts := config.Retries + 1
+
+	for attempt := 1; ; attempt++ {
+		err := runLighthouse(ctx, config, name)
+		if err == nil {
+			err = checkReport(filepath.Join(config.OutputDir, name+".json"))
+		}
+		if err == nil {
+			err = checkBenchmark(config, filepath.Join(config.OutputDir, name+".json"))
+		}
+		if err == nil {
+			fmt.Fprintf(config.Output, "Attempt %d of %d succeeded\n", attempt, attempts)
+			return attempt, nil
+		}
+
+		fmt.Fprintf(config.Output, "Attempt %d of %d failed: %s\n", attempt, attempts, err)
+
+		// the caller gave up, so retrying makes no sense
+		if ctx.Err() != nil {
+			return attempt, microerror.Mask(err)
+		}
+
+		if attempt == attempts {
+			setAsideOutputs(config, name)
+			if attempts > 1 {
+				return attempt, microerror.Maskf(err, "giving up after %d attempts", attempts)
+			}
+			return attempt, microerror.Mask(err)
+		}
+
+		fmt.Fprintf(config.Output, "Retrying in %s\n", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return attempt, microerror.Mask(ctx.Err())
+		}
+		backoff *= 2
+	}
+}
+
+// setAsideOutputs renames the report, the other formats and the assets of a
+// failed run from <name>.* to <name>.broken.*, so they aren't taken for
+// usable reports, e.g. when comparing. They are kept to investigate the
+// failure.
+func setAsideOutputs(config Config, name string) {
+	dir := filepath.Join(config.OutputDir, filepath.Dir(name))
+	base := filepath.Base(name)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(config.Output, "Could not move the outputs of the failed run: %s\n", err)
+		return
+	}
+
+	formats := []string{}
+	for _, f := range outputFormats(config.OutputFormats) {
+		formats = append(formats, regexp.QuoteMeta(f))
+	}
+	outputExpr := regexp.MustCompile(`^` + regexp.QuoteMeta(base) + `\.((trace|devtoolslog)(-\d+)?\.json|` + strings.Join(formats, "|") + `)$`)
+
+	moved := 0
+	for _, f := range files {
+		match := outputExpr.FindStringSubmatch(f.Name())
+		if match == nil {
+			continue
+		}
+
+		err := os.Rename(filepath.Join(dir, f.Name()), filepath.Join(dir, base+".broken."+match[1]))
+		if err != nil {
+			fmt.Fprintf(config.Output, "Could not move the outputs of the failed run: %s\n", err)
+			return
+		}
+		moved++
+	}
+
+	if moved > 0 {
+		fmt.Fprintf(config.Output, "Moved the outputs of the failed run to %s\n", filepath.Join(dir, base+".broken.*"))
+	}
+}
+
+// categoryScores holds the category scores of a report as pointers, to
+// detect null values.
+type categoryScores struct {
+	Categories map[string]struct {
+		Score *float64 `json:"score"`
+	} `json:"categories"`
+}
+
+// checkReport returns a brokenReportError if lighthouse wrote a report for a
+// broken run, either with a runtime error or with categories lacking scores.
+func checkReport(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return microerror.Maskf(brokenReportError, "reading report: %s", err)
+	}
+
+	report, err := parser.ParseReportJSON(data)
+	if err != nil {
+		return microerror.Maskf(brokenReportError, "parsing report: %s", err)
+	}
+
+	if report.RuntimeError != nil && report.RuntimeError.Code != "" && report.RuntimeError.Code != "NO_ERROR" {
+		return microerror.Maskf(brokenReportError, "lighthouse runtime error %s: %s", report.RuntimeError.Code, report.RuntimeError.Message)
+	}
+
+	var scores categoryScores
+	err = json.Unmarshal(data, &scores)
+	if err != nil {
+		return microerror.Maskf(brokenReportError, "parsing report: %s", err)
+	}
+
+	missing := []string{}
+	for id, cat := range scores.Categories {
+		if cat.Score == nil {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return microerror.Maskf(brokenReportError, "categories without score: %v", missing)
+	}
+
+	return nil
+}
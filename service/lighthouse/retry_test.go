@@ -0,0 +1,131 @@
+package lighthouse
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestCheckReport checks the detection of reports from broken runs.
+func TestCheckReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lighthouse-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name   string
+		report string
+		broken bool
+	}{
+		{
+			name:   "ok",
+			report: `{"runtimeError": {"code": "NO_ERROR", "message": ""}, "categories": {"performance": {"score": 0.9}}}`,
+			broken: false,
+		},
+		{
+			name:   "no-fcp",
+			report: `{"runtimeError": {"code": "NO_FCP", "message": "The page did not paint any content."}, "categories": {"performance": {"score": 0.9}}}`,
+			broken: true,
+		},
+		{
+			name:   "null-score",
+			report: `{"categories": {"performance": {"score": null}, "seo": {"score": 1}}}`,
+			broken: true,
+		},
+		{
+			name:   "invalid",
+			report: `<html>`,
+			broken: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		path := filepath.Join(dir, tc.name+".json")
+		err := ioutil.WriteFile(path, []byte(tc.report), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = checkReport(path)
+		if tc.broken && !IsBrokenReportError(err) {
+			t.Errorf("%s: expected brokenReportError, got %v", tc.name, err)
+		}
+		if !tc.broken && err != nil {
+			t.Errorf("%s: expected no error, got %v", tc.name, err)
+		}
+	}
+
+	err = checkReport(filepath.Join(dir, "missing.json"))
+	if !IsBrokenReportError(err) {
+		t.Errorf("missing: expected brokenReportError, got %v", err)
+	}
+}
+
+// TestSetAsideOutputs checks that all outputs of a failed run are renamed,
+// and nothing else.
+func TestSetAsideOutputs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		run      string
+		formats  []string
+		files    []string
+		expected []string
+	}{
+		{
+			name:     "report only",
+			run:      "home",
+			files:    []string{"home.json", "home.meta.json", "home.run-1.json", "other.json"},
+			expected: []string{"home.broken.json", "home.meta.json", "home.run-1.json", "other.json"},
+		},
+		{
+			name:    "formats and assets",
+			run:     "home",
+			formats: []string{FormatJSON, "html", "csv"},
+			files:   []string{"home.json", "home.html", "home.csv", "home.trace.json", "home.devtoolslog.json", "home.trace-1.json", "home.summary.json"},
+			expected: []string{
+				"home.broken.csv", "home.broken.devtoolslog.json", "home.broken.html", "home.broken.json",
+				"home.broken.trace-1.json", "home.broken.trace.json", "home.summary.json",
+			},
+		},
+		{
+			name:     "run of several",
+			run:      "site/home.run-2",
+			formats:  []string{"html"},
+			files:    []string{"site/home.run-1.json", "site/home.run-2.json", "site/home.run-2.html"},
+			expected: []string{"site/home.run-1.json", "site/home.run-2.broken.html", "site/home.run-2.broken.json"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer inTempDir(t)()
+
+			for _, f := range tc.files {
+				err := os.MkdirAll(filepath.Dir(f), 0755)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = ioutil.WriteFile(f, []byte("{}"), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var out bytes.Buffer
+			setAsideOutputs(Config{OutputDir: ".", OutputFormats: tc.formats, Output: &out}, tc.run)
+
+			files, err := filepath.Glob(filepath.Join(filepath.Dir(tc.run), "*.*"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(files, tc.expected) {
+				t.Errorf("expected files %v, got %v", tc.expected, files)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package lighthouse
+
+import (
+	"context"
+	"io"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Supported runtimes to execute lighthouse with.
+const (
+	RuntimeDocker = "docker"
+	// RuntimeDockerAPI runs the container through the Docker Engine API
+	// instead of the docker command line client.
+	RuntimeDockerAPI = "docker-api"
+	RuntimePodman    = "podman"
+	RuntimeNative    = "native"
+)
+
+// Runner executes lighthouse.
+type Runner interface {
+	// Prepare is called once before the first Run, for example to pull
+	// the image.
+	Prepare(ctx context.Context, out io.Writer) error
+	// Metadata describes the runtime for the report metadata.
+	Metadata() Metadata
+	// Run executes lighthouse with the given job and returns once the
+	// report has been written. Run must stop lighthouse and clean up once
+	// ctx is done.
+	Run(ctx context.Context, job Job) error
+	// Exec runs an arbitrary command in the environment lighthouse runs
+	// in, for example to probe the audited site, and returns its standard
+	// output.
+	Exec(ctx context.Context, args []string) ([]byte, error)
+}
+
+// Job is a single lighthouse invocation.
+type Job struct {
+	// WorkDir is the host directory lighthouse runs in. Paths in Args are
+	// relative to it.
+	WorkDir string
+	// Name is the report name relative to WorkDir. All files lighthouse
+	// writes for the job start with it.
+	Name string
+	// Command is the program to run instead of lighthouse, e.g. node for
+	// a script driving lighthouse.
+	Command string
+	// Args are the command line arguments, without the lighthouse binary
+	// or Command itself.
+	Args []string
+	// Mounts are absolute paths of host directories lighthouse reads
+	// files from besides WorkDir. Container runtimes mount them read-only
+	// at the same path, so Args can refer to them by their host path.
+	Mounts []string
+	// SecretMounts are mounted like Mounts, but hold secrets only the
+	// invoking user may read. Container runners hand them over to the
+	// container user.
+	SecretMounts []string
+	// Output receives the output of lighthouse. Runners that can't stream
+	// it write at least the error output of failed runs.
+	Output io.Writer
+}
+
+// RunnerConfig holds the settings to create a Runner.
+type RunnerConfig struct {
+	// Runtime is one of RuntimeDocker, RuntimeDockerAPI, RuntimePodman or
+	// RuntimeNative. Defaults to RuntimeDocker.
+	Runtime string
+	// DockerLinks are passed to the container as --link flags. Not
+	// supported by the native runtime.
+	DockerLinks []string
+	// Network is the container network to connect lighthouse to, or
+	// NetworkHost to use the host's network. Not supported by the native
+	// runtime.
+	Network string
+	// ExtraHosts are passed to the container as --add-host flags, in the
+	// form host:ip. Not supported by the native runtime.
+	ExtraHosts []string
+
+	// Image is the container image name. Defaults to DefaultImage.
+	Image string
+	// ImageTag is the tag of Image. Defaults to DefaultImageTag.
+	ImageTag string
+	// ImageDigest pins the image by digest, overriding ImageTag.
+	ImageDigest string
+	// ImageArchive is a tarball to load the image from instead of pulling.
+	ImageArchive string
+	// Pull is one of PullAlways, PullMissing or PullNever. Defaults to
+	// PullMissing.
+	Pull string
+	// User is UserAuto, UserImage or a numeric uid:gid to run the
+	// container as. Defaults to UserAuto. Not supported by the native
+	// runtime, which always runs as the invoking user.
+	User string
+	// Resources limit the resources of the lighthouse container. Not
+	// supported by the native runtime.
+	Resources Resources
+}
+
+// NewRunner returns the Runner for the configured runtime.
+func NewRunner(config RunnerConfig) (Runner, error) {
+	err := validateImageConfig(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = validateNetworkConfig(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = validateUserConfig(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = config.Resources.Validate()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	switch config.Runtime {
+	case RuntimeDocker, "":
+		return newContainerRunner("docker", config), nil
+	case RuntimeDockerAPI:
+		return newEngineRunner(config)
+	case RuntimePodman:
+		return newContainerRunner("podman", config), nil
+	case RuntimeNative:
+		if len(config.DockerLinks) > 0 || config.Network != "" || len(config.ExtraHosts) > 0 {
+			return nil, microerror.Maskf(invalidConfigError, "container networking is not supported by the %q runtime", RuntimeNative)
+		}
+		if !config.Resources.IsZero() {
+			return nil, microerror.Maskf(invalidConfigError, "resource limits are not supported by the %q runtime", RuntimeNative)
+		}
+		return newNativeRunner()
+	}
+
+	return nil, microerror.Maskf(invalidConfigError, "unknown runtime %q, must be one of %q, %q, %q or %q", config.Runtime, RuntimeDocker, RuntimeDockerAPI, RuntimePodman, RuntimeNative)
+}
+
+// contextError returns the error for a run stopped because ctx is done.
+func contextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return microerror.Maskf(timeoutError, "lighthouse did not finish in time")
+	}
+
+	return microerror.Mask(ctx.Err())
+}
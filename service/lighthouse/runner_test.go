@@ -0,0 +1,321 @@
+package lighthouse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/lighthouse-keeper/service/parser"
+)
+
+// fakeRunner writes fixture reports from the parser testdata instead of
+// running lighthouse. Fixtures are used in turn, URLs containing "fail" make
+// the run fail and URLs containing "hang" block until ctx is done. URLs
+// containing "flaky" fail on the first attempt only. Exec returns the given
+// outputs in turn, an empty output makes Exec fail. The contents of extra
+// header files are recorded, as they are removed after the run. URLs
+// containing "login-fail" make the login script fail like the login driver
+// reports it. Like lighthouse, it writes <name>.report.<format> files for
+// several output formats and numbered assets.
+type fakeRunner struct {
+	fixtures    []string
+	execOutputs []string
+	resources   *Resources
+
+	mutex        sync.Mutex
+	jobs         []Job
+	extraHeaders []string
+	attempts     map[string]int
+}
+
+func newFakeRunner(t *testing.T) *fakeRunner {
+	r := &fakeRunner{attempts: map[string]int{}}
+	for _, f := range []string{"../parser/testdata/001.json", "../parser/testdata/002.json"} {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.fixtures = append(r.fixtures, abs)
+	}
+
+	return r
+}
+
+func (r *fakeRunner) Prepare(ctx context.Context, out io.Writer) error {
+	return nil
+}
+
+func (r *fakeRunner) Metadata() Metadata {
+	return Metadata{Runtime: "fake", Resources: r.resources}
+}
+
+func (r *fakeRunner) Exec(ctx context.Context, args []string) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.execOutputs) == 0 {
+		return nil, microerror.Maskf(runFailedError, "no fake exec output left")
+	}
+	output := r.execOutputs[0]
+	r.execOutputs = r.execOutputs[1:]
+
+	if output == "" {
+		return nil, microerror.Maskf(runFailedError, "fake exec failure")
+	}
+
+	return []byte(output), nil
+}
+
+func (r *fakeRunner) Run(ctx context.Context, job Job) error {
+	r.mutex.Lock()
+	fixture := r.fixtures[len(r.jobs)%len(r.fixtures)]
+	r.jobs = append(r.jobs, job)
+	url := job.Args[len(job.Args)-1]
+	r.attempts[url]++
+	attempt := r.attempts[url]
+	r.mutex.Unlock()
+
+	if strings.Contains(url, "flaky") && attempt == 1 {
+		return microerror.Maskf(runFailedError, "fake first attempt failure for %s", url)
+	}
+	if strings.Contains(url, "hang") {
+		<-ctx.Done()
+		return contextError(ctx)
+	}
+	if strings.Contains(url, "login-fail") && job.Command == "node" {
+		return ioutil.WriteFile(filepath.Join(job.WorkDir, job.Args[3]), []byte("fake login failure"), 0644)
+	}
+	if strings.Contains(url, "fail") {
+		return microerror.Maskf(runFailedError, "fake failure for %s", url)
+	}
+
+	var outputPath string
+	var formats []string
+	saveAssets := false
+	for _, a := range job.Args {
+		if strings.HasPrefix(a, "--output-path=") {
+			outputPath = strings.TrimPrefix(a, "--output-path=")
+		}
+		if strings.HasPrefix(a, "--output=") {
+			formats = append(formats, strings.TrimPrefix(a, "--output="))
+		}
+		if a == "--save-assets" {
+			saveAssets = true
+		}
+		if strings.HasPrefix(a, "--extra-headers=") {
+			data, err := ioutil.ReadFile(strings.TrimPrefix(a, "--extra-headers="))
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			r.mutex.Lock()
+			r.extraHeaders = append(r.extraHeaders, string(data))
+			r.mutex.Unlock()
+		}
+	}
+
+	data, err := ioutil.ReadFile(fixture)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	base := filepath.Join(job.WorkDir, strings.TrimSuffix(outputPath, ".json"))
+	if saveAssets {
+		for _, asset := range []string{"-0.trace.json", "-0.devtoolslog.json"} {
+			err := ioutil.WriteFile(base+asset, []byte("{}"), 0644)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+	}
+
+	if len(formats) < 2 {
+		return ioutil.WriteFile(filepath.Join(job.WorkDir, outputPath), data, 0644)
+	}
+	for _, f := range formats {
+		content := data
+		if f != FormatJSON {
+			content = []byte(fmt.Sprintf("fake %s of %s", f, fixture))
+		}
+		err := ioutil.WriteFile(base+".report."+f, content, 0644)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// inTempDir changes into a new temporary directory for the duration of a
+// test and returns a function to change back and clean up.
+func inTempDir(t *testing.T) func() {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "lighthouse-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		os.Chdir(pwd)
+		os.RemoveAll(dir)
+	}
+}
+
+// TestAuditURLRuns checks that the median of several runs is written along
+// with the individual runs and the summary.
+func TestAuditURLRuns(t *testing.T) {
+	runner := newFakeRunner(t)
+
+	defer inTempDir(t)()
+
+	config := Config{
+		URL:    "https://example.com/",
+		Name:   "example",
+		Runs:   3,
+		Runner: runner,
+		Output: &bytes.Buffer{},
+	}
+
+	path, err := AuditURL(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "example.json" {
+		t.Errorf("expected path example.json, got %q", path)
+	}
+
+	for _, name := range []string{"example.run-1.json", "example.run-2.json", "example.run-3.json", "example.summary.json", "example.meta.json"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("expected file %q: %s", name, err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := parser.ParseReportJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// runs 1 and 3 use fixture 001 with performance 0.82, run 2 uses 002 with 0.86
+	if report.Categories["performance"].Score != 0.82 {
+		t.Errorf("expected median performance score 0.82, got %f", report.Categories["performance"].Score)
+	}
+}
+
+// TestAuditURLOutputDir checks that reports are written to the output
+// directory, including subdirectories of the name.
+func TestAuditURLOutputDir(t *testing.T) {
+	runner := newFakeRunner(t)
+
+	defer inTempDir(t)()
+
+	config := Config{
+		URL:       "https://example.com/",
+		Name:      "example.com/index",
+		OutputDir: "reports",
+		Runs:      2,
+		Runner:    runner,
+		Output:    &bytes.Buffer{},
+	}
+
+	path, err := AuditURL(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join("reports", "example.com", "index.json") {
+		t.Errorf("unexpected path %q", path)
+	}
+
+	for _, name := range []string{"index.run-1.json", "index.run-2.json", "index.summary.json", "index.meta.json"} {
+		if _, err := os.Stat(filepath.Join("reports", "example.com", name)); err != nil {
+			t.Errorf("expected file %q: %s", name, err)
+		}
+	}
+
+	job := runner.jobs[0]
+	if !strings.HasSuffix(job.WorkDir, "reports") {
+		t.Errorf("expected work dir reports, got %q", job.WorkDir)
+	}
+}
+
+// TestAuditURLTimeout checks that a hanging run ends with a timeoutError.
+func TestAuditURLTimeout(t *testing.T) {
+	runner := newFakeRunner(t)
+	defer inTempDir(t)()
+
+	config := Config{
+		URL:     "https://example.com/hang",
+		Name:    "hang",
+		Runner:  runner,
+		Output:  &bytes.Buffer{},
+		Timeout: 10 * time.Millisecond,
+	}
+
+	_, err := AuditURL(context.Background(), config)
+	if !IsTimeoutError(err) {
+		t.Errorf("expected timeoutError, got %v", err)
+	}
+}
+
+// TestAuditURLRetries checks that failed runs are retried and the number of
+// attempts is recorded.
+func TestAuditURLRetries(t *testing.T) {
+	runner := newFakeRunner(t)
+	defer inTempDir(t)()
+
+	var out bytes.Buffer
+	config := Config{
+		URL:          "https://example.com/flaky",
+		Name:         "flaky",
+		Runner:       runner,
+		Output:       &out,
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+	}
+
+	path, err := AuditURL(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"Attempt 1 of 3 failed: ", "Attempt 2 of 3 succeeded"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected %q in output:\n%s", line, out.String())
+		}
+	}
+
+	meta, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", meta.Attempts)
+	}
+
+	config.URL = "https://example.com/fail"
+	config.Name = "fail"
+	_, err = AuditURL(context.Background(), config)
+	if !IsRunFailedError(err) {
+		t.Errorf("expected runFailedError, got %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package lighthouse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/lighthouse-keeper/service/parser"
+)
+
+// DefaultMedianMetric is the score used to pick the median run if no other
+// metric is configured.
+const DefaultMedianMetric = "performance"
+
+// Summary describes the spread of scores over several runs of the same audit.
+type Summary struct {
+	Runs       int                        `json:"runs"`
+	Metric     string                     `json:"metric"`
+	MedianRun  int                        `json:"medianRun"`
+	MedianFile string                     `json:"medianFile"`
+	Attempts   []int                      `json:"attempts"`
+	Categories map[string]CategorySummary `json:"categories"`
+	// BenchmarkIndexes are the benchmark indexes of the runs, in run
+	// order.
+	BenchmarkIndexes []float64 `json:"benchmarkIndexes,omitempty"`
+}
+
+// CategorySummary holds the per-run scores of one category, in run order.
+type CategorySummary struct {
+	Title  string    `json:"title"`
+	Scores []float32 `json:"scores"`
+	Min    float32   `json:"min"`
+	Max    float32   `json:"max"`
+	Median float32   `json:"median"`
+	Spread float32   `json:"spread"`
+}
+
+// metricScore returns the score of the category or audit with the given ID.
+func metricScore(report *parser.Report, metric string) (float32, error) {
+	if cat, ok := report.Categories[metric]; ok {
+		return cat.Score, nil
+	}
+	if audit, ok := report.Audits[metric]; ok {
+		return audit.Score, nil
+	}
+
+	return 0, microerror.Maskf(unknownMetricError, "report has no category or audit %q", metric)
+}
+
+// medianIndex returns the index of the report with the median score for the
+// given metric. For an even number of reports the lower median is used.
+func medianIndex(reports []*parser.Report, metric string) (int, error) {
+	indexes := make([]int, len(reports))
+	scores := make([]float32, len(reports))
+	for i, r := range reports {
+		score, err := metricScore(r, metric)
+		if err != nil {
+			return 0, microerror.Mask(err)
+		}
+		indexes[i] = i
+		scores[i] = score
+	}
+
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return scores[indexes[a]] < scores[indexes[b]]
+	})
+
+	return indexes[(len(indexes)-1)/2], nil
+}
+
+// summarize collects the per-category scores of all runs.
+func summarize(reports []*parser.Report) map[string]CategorySummary {
+	categories := map[string]CategorySummary{}
+
+	for _, r := range reports {
+		for id, cat := range r.Categories {
+			s := categories[id]
+			s.Title = cat.Title
+			s.Scores = append(s.Scores, cat.Score)
+			categories[id] = s
+		}
+	}
+
+	for id, s := range categories {
+		sorted := append([]float32{}, s.Scores...)
+		sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+
+		s.Min = sorted[0]
+		s.Max = sorted[len(sorted)-1]
+		s.Median = sorted[(len(sorted)-1)/2]
+		s.Spread = s.Max - s.Min
+		categories[id] = s
+	}
+
+	return categories
+}
+
+// writeMedianReport reads the reports of all runs, copies the median one to
+// path and returns the summary of all runs.
+func writeMedianReport(runPaths []string, path, metric string) (*Summary, error) {
+	reports := []*parser.Report{}
+	blobs := [][]byte{}
+
+	for _, p := range runPaths {
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		report, err := parser.ParseReportJSON(data)
+		if err != nil {
+			return nil, microerror.Maskf(err, "parsing report %q", p)
+		}
+
+		reports = append(reports, report)
+		blobs = append(blobs, data)
+	}
+
+	median, err := medianIndex(reports, metric)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = ioutil.WriteFile(path, blobs[median], 0644)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// the summary is written next to the runs, so it refers to them by
+	// file name
+	summary := &Summary{
+		Runs:       len(reports),
+		Metric:     metric,
+		MedianRun:  median + 1,
+		MedianFile: filepath.Base(runPaths[median]),
+		Categories: summarize(reports),
+	}
+
+	return summary, nil
+}
+
+func writeSummary(summary *Summary, path string) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = ioutil.WriteFile(path, data, 0644)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func printSummary(out io.Writer, summary *Summary) {
+	fmt.Fprintf(out, "Median run by %s: %d of %d\n", summary.Metric, summary.MedianRun, summary.Runs)
+
+	ids := []string{}
+	for id := range summary.Categories {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		s := summary.Categories[id]
+		fmt.Fprintf(out, "- %s: median %.0f, min %.0f, max %.0f, spread %.0f\n", s.Title, s.Median*100, s.Min*100, s.Max*100, s.Spread*100)
+	}
+
+	if len(summary.BenchmarkIndexes) > 0 {
+		indexes := []string{}
+		for _, i := range summary.BenchmarkIndexes {
+			indexes = append(indexes, fmt.Sprintf("%.0f", i))
+		}
+		fmt.Fprintf(out, "Benchmark index per run: %s\n", strings.Join(indexes, ", "))
+	}
+}
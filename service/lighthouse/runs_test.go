@@ -0,0 +1,75 @@
+package lighthouse
+
+import (
+	"testing"
+
+	"github.com/giantswarm/lighthouse-keeper/service/parser"
+)
+
+func reportWithScores(performance, seo float32) *parser.Report {
+	return &parser.Report{
+		Categories: map[string]parser.Category{
+			"performance": {ID: "performance", Title: "Performance", Score: performance},
+			"seo":         {ID: "seo", Title: "SEO", Score: seo},
+		},
+		Audits: map[string]parser.Audit{
+			"speed-index": {ID: "speed-index", Score: seo},
+		},
+	}
+}
+
+// TestMedianIndex checks that the run with the median score is picked.
+func TestMedianIndex(t *testing.T) {
+	reports := []*parser.Report{
+		reportWithScores(0.90, 0.50),
+		reportWithScores(0.70, 0.80),
+		reportWithScores(0.80, 0.60),
+		reportWithScores(0.60, 0.70),
+	}
+
+	testCases := []struct {
+		metric   string
+		expected int
+	}{
+		{"performance", 1},
+		{"seo", 2},
+		{"speed-index", 2},
+	}
+
+	for _, tc := range testCases {
+		index, err := medianIndex(reports, tc.metric)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if index != tc.expected {
+			t.Errorf("metric %q: expected run index %d, got %d", tc.metric, tc.expected, index)
+		}
+	}
+
+	_, err := medianIndex(reports, "no-such-metric")
+	if !IsUnknownMetricError(err) {
+		t.Errorf("expected unknownMetricError, got %v", err)
+	}
+}
+
+// TestSummarize checks the per-category spread over several runs.
+func TestSummarize(t *testing.T) {
+	reports := []*parser.Report{
+		reportWithScores(0.90, 0.50),
+		reportWithScores(0.70, 0.50),
+		reportWithScores(0.80, 0.50),
+	}
+
+	summary := summarize(reports)
+
+	perf := summary["performance"]
+	if perf.Min != 0.70 || perf.Max != 0.90 || perf.Median != 0.80 {
+		t.Errorf("unexpected performance summary %+v", perf)
+	}
+	if len(perf.Scores) != 3 || perf.Scores[0] != 0.90 {
+		t.Errorf("expected scores in run order, got %v", perf.Scores)
+	}
+	if summary["seo"].Spread != 0 {
+		t.Errorf("expected no spread for seo, got %f", summary["seo"].Spread)
+	}
+}
@@ -0,0 +1,88 @@
+package lighthouse
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Settings are the lighthouse settings a report was created with. Two
+// reports are only comparable if they were created with the same settings.
+type Settings struct {
+	FormFactor string     `json:"formFactor"`
+	Preset     string     `json:"preset,omitempty"`
+	Throttling Throttling `json:"throttling"`
+	Screen     Screen     `json:"screen"`
+	// ConfigDigest is the SHA-256 checksum of the content of the
+	// lighthouse config file, if any. Its path is not recorded, it
+	// differs between checkouts of the same config.
+	ConfigDigest   string   `json:"configDigest,omitempty"`
+	OnlyCategories []string `json:"onlyCategories,omitempty"`
+	OnlyAudits     []string `json:"onlyAudits,omitempty"`
+	SkipAudits     []string `json:"skipAudits,omitempty"`
+	// LoginScript is the script run before the audit, if any.
+	LoginScript string `json:"loginScript,omitempty"`
+	// ExtraHeaderNames are the names of the extra headers sent. Their
+	// values are not recorded as they may contain secrets.
+	ExtraHeaderNames []string `json:"extraHeaderNames,omitempty"`
+	// Resources are the resource limits of the lighthouse container, if
+	// any. Scores of differently limited containers are not comparable.
+	Resources *Resources `json:"resources,omitempty"`
+}
+
+// settings returns the Settings for the given config.
+func settings(config Config) (Settings, error) {
+	s := Settings{
+		FormFactor:     config.FormFactor,
+		Preset:         config.Preset,
+		Throttling:     config.Throttling,
+		Screen:         config.Screen,
+		OnlyCategories: config.OnlyCategories,
+		OnlyAudits:     config.OnlyAudits,
+		SkipAudits:     config.SkipAudits,
+		LoginScript:    config.LoginScript,
+	}
+
+	if len(config.ExtraHeaders) > 0 {
+		s.ExtraHeaderNames = headerNames(config.ExtraHeaders)
+	}
+
+	if config.Runner != nil {
+		s.Resources = config.Runner.Metadata().Resources
+	}
+
+	if config.ConfigPath != "" {
+		data, err := ioutil.ReadFile(config.ConfigPath)
+		if err != nil {
+			return Settings{}, microerror.Maskf(invalidConfigError, "reading lighthouse config: %s", err)
+		}
+		s.ConfigDigest = fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	}
+
+	return s, nil
+}
+
+// DiffSettings returns a description of each setting that differs between
+// a and b, using their JSON names.
+func DiffSettings(a, b Settings) []string {
+	diffs := []string{}
+
+	va := reflect.ValueOf(a)
+	vb := reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		fa := va.Field(i).Interface()
+		fb := vb.Field(i).Interface()
+		if reflect.DeepEqual(fa, fb) {
+			continue
+		}
+
+		name := strings.Split(va.Type().Field(i).Tag.Get("json"), ",")[0]
+		diffs = append(diffs, fmt.Sprintf("%s: %v != %v", name, fa, fb))
+	}
+
+	return diffs
+}
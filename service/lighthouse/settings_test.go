@@ -0,0 +1,113 @@
+package lighthouse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestDiffSettings checks the description of differing settings.
+func TestDiffSettings(t *testing.T) {
+	a := Settings{
+		FormFactor:     "desktop",
+		OnlyCategories: []string{"accessibility"},
+	}
+	b := Settings{
+		FormFactor:     "desktop",
+		OnlyCategories: []string{"accessibility"},
+	}
+
+	diffs := DiffSettings(a, b)
+	if len(diffs) != 0 {
+		t.Errorf("expected no differences, got %v", diffs)
+	}
+
+	a.Resources = &Resources{CPUs: 2}
+	b.Resources = &Resources{CPUs: 2}
+	diffs = DiffSettings(a, b)
+	if len(diffs) != 0 {
+		t.Errorf("expected no differences, got %v", diffs)
+	}
+
+	b.FormFactor = "mobile"
+	b.SkipAudits = []string{"uses-http2"}
+	b.Resources = &Resources{CPUs: 1, Memory: 1024 * 1024 * 1024}
+	diffs = DiffSettings(a, b)
+	expected := []string{
+		"formFactor: desktop != mobile",
+		"skipAudits: [] != [uses-http2]",
+		"resources: --cpus=2 != --cpus=1 --memory=1073741824",
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("expected %v, got %v", expected, diffs)
+	}
+}
+
+// TestSettingsConfig checks that the lighthouse config file is compared by
+// content, not by the path it was given with.
+func TestSettingsConfig(t *testing.T) {
+	defer inTempDir(t)()
+
+	for name, content := range map[string]string{"a.js": "module.exports = {};", "b.js": "module.exports = {extends: 'lighthouse:default'};"} {
+		err := ioutil.WriteFile(name, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		a, b          string
+		expectedDiffs int
+	}{
+		{"a.js", "a.js", 0},
+		{"a.js", filepath.Join(wd, "a.js"), 0},
+		{"./a.js", "b.js", 1},
+	}
+
+	for _, tc := range testCases {
+		a, err := settings(Config{ConfigPath: tc.a})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := settings(Config{ConfigPath: tc.b})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		diffs := DiffSettings(a, b)
+		if len(diffs) != tc.expectedDiffs {
+			t.Errorf("%s and %s: expected %d differences, got %v", tc.a, tc.b, tc.expectedDiffs, diffs)
+		}
+	}
+}
+
+// TestSettingsResources checks that the resource limits of the runner are
+// part of the settings.
+func TestSettingsResources(t *testing.T) {
+	testCases := []struct {
+		resources *Resources
+		expected  *Resources
+	}{
+		{nil, nil},
+		{&Resources{CPUs: 1.5, CPUSet: "0-1"}, &Resources{CPUs: 1.5, CPUSet: "0-1"}},
+	}
+
+	for _, tc := range testCases {
+		runner := newFakeRunner(t)
+		runner.resources = tc.resources
+
+		s, err := settings(Config{Runner: runner})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(s.Resources, tc.expected) {
+			t.Errorf("expected resources %v, got %v", tc.expected, s.Resources)
+		}
+	}
+}
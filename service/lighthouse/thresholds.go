@@ -0,0 +1,93 @@
+package lighthouse
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/lighthouse-keeper/service/parser"
+)
+
+// Thresholds are the minimum scores of categories, by category ID. Scores
+// range from 0 to 100, as lighthouse displays them.
+type Thresholds map[string]float64
+
+// ParseThreshold splits a threshold given as "category=score", e.g.
+// "performance=90".
+func ParseThreshold(s string) (string, float64, error) {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		return "", 0, microerror.Maskf(invalidConfigError, "threshold %q must have the form 'category=score'", s)
+	}
+
+	score, err := strconv.ParseFloat(strings.TrimSpace(s[i+1:]), 64)
+	if err != nil {
+		return "", 0, microerror.Maskf(invalidConfigError, "threshold %q must have a numeric score", s)
+	}
+
+	return strings.TrimSpace(s[:i]), score, nil
+}
+
+// Validate checks that all scores are between 0 and 100.
+func (t Thresholds) Validate() error {
+	for category, score := range t {
+		if category == "" {
+			return microerror.Maskf(invalidConfigError, "threshold category must not be empty")
+		}
+		if score < 0 || score > 100 {
+			return microerror.Maskf(invalidConfigError, "threshold %g of %q must be between 0 and 100", score, category)
+		}
+	}
+
+	return nil
+}
+
+// checkThresholds returns a thresholdError listing the categories of the
+// report at the given path that score below their threshold.
+func checkThresholds(config Config, path string) error {
+	if len(config.Thresholds) == 0 {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	report, err := parser.ParseReportJSON(data)
+	if err != nil {
+		return microerror.Maskf(brokenReportError, "parsing report: %s", err)
+	}
+
+	categories := []string{}
+	for category := range config.Thresholds {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	problems := []string{}
+	for _, category := range categories {
+		min := config.Thresholds[category]
+		cat, ok := report.Categories[category]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is missing from the report", category))
+			continue
+		}
+
+		// compare the score as displayed, 0.9 is stored as 0.8999...
+		score := math.Round(float64(cat.Score) * 100)
+		if score < min {
+			problems = append(problems, fmt.Sprintf("%s score %.0f is below the threshold %g", category, score, min))
+		}
+	}
+
+	if len(problems) > 0 {
+		return microerror.Maskf(thresholdError, "%s", strings.Join(problems, ", "))
+	}
+
+	return nil
+}
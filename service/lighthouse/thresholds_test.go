@@ -0,0 +1,113 @@
+package lighthouse
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestParseThreshold checks parsing of thresholds given as flags.
+func TestParseThreshold(t *testing.T) {
+	testCases := []struct {
+		name             string
+		input            string
+		expectedCategory string
+		expectedScore    float64
+		errorMatcher     func(error) bool
+	}{
+		{
+			name:             "valid",
+			input:            "performance=90",
+			expectedCategory: "performance",
+			expectedScore:    90,
+		},
+		{
+			name:             "spaces and fraction",
+			input:            "best-practices = 87.5",
+			expectedCategory: "best-practices",
+			expectedScore:    87.5,
+		},
+		{
+			name:         "missing score",
+			input:        "performance",
+			errorMatcher: IsInvalidConfigError,
+		},
+		{
+			name:         "missing category",
+			input:        "=90",
+			errorMatcher: IsInvalidConfigError,
+		},
+		{
+			name:         "non-numeric score",
+			input:        "seo=high",
+			errorMatcher: IsInvalidConfigError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			category, score, err := ParseThreshold(tc.input)
+			if tc.errorMatcher != nil {
+				if !tc.errorMatcher(err) {
+					t.Fatalf("unexpected error %#v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if category != tc.expectedCategory || score != tc.expectedScore {
+				t.Errorf("expected %s=%g, got %s=%g", tc.expectedCategory, tc.expectedScore, category, score)
+			}
+
+			err = Thresholds{category: score}.Validate()
+			if err != nil {
+				t.Errorf("unexpected validation error %s", err)
+			}
+		})
+	}
+
+	err := Thresholds{"performance": 101}.Validate()
+	if !IsInvalidConfigError(err) {
+		t.Errorf("expected a score above 100 to be rejected, got %#v", err)
+	}
+}
+
+// TestAuditURLThresholds checks that reports scoring below a threshold fail
+// the audit but are kept. Fixture 001 scores 82 in performance and 90 in
+// seo.
+func TestAuditURLThresholds(t *testing.T) {
+	passRunner := newFakeRunner(t)
+	failRunner := newFakeRunner(t)
+
+	defer inTempDir(t)()
+
+	config := Config{
+		URL:        "https://example.com/",
+		Name:       "pass",
+		Runner:     passRunner,
+		Output:     ioutil.Discard,
+		Thresholds: Thresholds{"performance": 82, "seo": 90},
+	}
+
+	_, err := AuditURL(context.Background(), config)
+	if err != nil {
+		t.Fatalf("expected scores matching the thresholds to pass, got %s", err)
+	}
+
+	config.Name = "fail"
+	config.Runner = failRunner
+	config.Thresholds = Thresholds{"performance": 90, "seo": 90, "custom": 50}
+	path, err := AuditURL(context.Background(), config)
+	if !IsThresholdError(err) {
+		t.Fatalf("expected a threshold error, got %#v", err)
+	}
+	if path != "fail.json" {
+		t.Errorf("expected the report path along with the error, got %q", path)
+	}
+	expected := "custom is missing from the report, performance score 82 is below the threshold 90"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected %q in %q", expected, err.Error())
+	}
+}
@@ -0,0 +1,207 @@
+package lighthouse
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Settings for the user the lighthouse container runs as.
+const (
+	// UserAuto runs the container as the invoking user, so reports in the
+	// mounted work directory belong to them. Rootless runtimes already map
+	// the container's root user to the invoking user and run unchanged.
+	UserAuto = "auto"
+	// UserImage runs the container as the user configured in the image
+	// and hands the written files over to the invoking user afterwards,
+	// for images that don't work with an arbitrary user.
+	UserImage = "image"
+)
+
+var numericUserRegexp = regexp.MustCompile(`^[0-9]+(:[0-9]+)?$`)
+
+func validateUserConfig(config RunnerConfig) error {
+	switch config.User {
+	case UserAuto, "":
+		return nil
+	case UserImage:
+	default:
+		if !numericUserRegexp.MatchString(config.User) {
+			return microerror.Maskf(invalidConfigError, "user %q must be %q, %q or a numeric uid:gid", config.User, UserAuto, UserImage)
+		}
+	}
+
+	if config.Runtime == RuntimeNative {
+		return microerror.Maskf(invalidConfigError, "a container user is not supported by the %q runtime", RuntimeNative)
+	}
+
+	return nil
+}
+
+// containerUser decides how files written by the container end up belonging
+// to the invoking user with the given uid and gid. It returns the value for
+// docker run --user, if any, and the owner to change the files to after the
+// run, if any.
+func containerUser(setting string, uid, gid int, rootless bool) (string, string) {
+	switch {
+	case setting != UserAuto && setting != UserImage && setting != "":
+		return setting, ""
+	case uid < 0, uid == 0, rootless:
+		// On Windows there are no uids to map, root owns the files
+		// already, and rootless runtimes write as the invoking user.
+		return "", ""
+	case setting == UserImage:
+		return "", fmt.Sprintf("%d:%d", uid, gid)
+	}
+
+	return fmt.Sprintf("%d:%d", uid, gid), ""
+}
+
+// parseRootless tells from the output of rootlessInfoArgs whether the
+// runtime runs rootless.
+func parseRootless(binary, info string) bool {
+	if binary == "podman" {
+		return strings.TrimSpace(info) == "true"
+	}
+
+	// docker lists name=rootless among its security options
+	return strings.Contains(info, "name=rootless")
+}
+
+// rootlessInfoArgs returns the info command printing whether the runtime
+// runs rootless.
+func rootlessInfoArgs(binary string) []string {
+	if binary == "podman" {
+		return []string{"info", "--format", "{{.Host.Security.Rootless}}"}
+	}
+
+	return []string{"info", "--format", "{{json .SecurityOptions}}"}
+}
+
+// isRootless asks the runtime whether it runs rootless.
+func (r *containerRunner) isRootless(ctx context.Context) (bool, error) {
+	info, err := r.command(ctx, rootlessInfoArgs(r.binary)...)
+	if err != nil {
+		return false, microerror.Maskf(err, "could not query %s info", r.binary)
+	}
+
+	return parseRootless(r.binary, info), nil
+}
+
+// userArgs returns the docker run flags setting the container user.
+func (r *containerRunner) userArgs() []string {
+	if r.runAs == "" {
+		return nil
+	}
+
+	// the user likely has no home directory in the image, Chrome needs
+	// a writable one
+	return []string{fmt.Sprintf("--user=%s", r.runAs), "--env=HOME=/tmp"}
+}
+
+// sharedMemoryDir creates the host directory mounted as the container's
+// /dev/shm. TempDir creates it for the invoking user only, so it is opened
+// up for containers running as another user.
+func sharedMemoryDir(runAs string) (string, error) {
+	dir, err := ioutil.TempDir("/tmp", "lighthouse-temp")
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	if runAs == "" {
+		return dir, nil
+	}
+
+	err = os.Chmod(dir, os.ModeSticky|0777)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", microerror.Mask(err)
+	}
+
+	return dir, nil
+}
+
+// handOverSecrets changes the owner of the secret directories and their
+// files to the container user, as only their owner may read them. Only root
+// may do so for another user than the invoking one.
+func handOverSecrets(dirs []string, runAs string) error {
+	if runAs == "" || len(dirs) == 0 {
+		return nil
+	}
+
+	ids := strings.SplitN(runAs, ":", 2)
+	uid, err := strconv.Atoi(ids[0])
+	if err != nil {
+		return microerror.Maskf(invalidConfigError, "user %q must be a numeric uid:gid", runAs)
+	}
+	gid := -1
+	if len(ids) == 2 {
+		gid, err = strconv.Atoi(ids[1])
+		if err != nil {
+			return microerror.Maskf(invalidConfigError, "user %q must be a numeric uid:gid", runAs)
+		}
+	}
+	if uid == os.Getuid() {
+		return nil
+	}
+
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Chown(path, uid, gid)
+		})
+		if os.IsPermission(err) {
+			return microerror.Maskf(invalidConfigError, "container user %s can not read the extra headers, run lighthouse-keeper as root or as uid %d to send them", runAs, uid)
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// chownOutputs hands the files lighthouse wrote for the job over to the
+// invoking user. Only root may change their owner, whichever user the image
+// is configured with.
+func (r *containerRunner) chownOutputs(job Job) error {
+	args := []string{"--user=0", fmt.Sprintf("-v=%s:/workdir", job.WorkDir), r.image}
+	args = append(args, chownCommand(job, r.chownTo)...)
+
+	_, stderr, err := r.runContainer(context.Background(), args, job.Output)
+	if err != nil {
+		return microerror.Maskf(err, "changing the owner of the reports to %s: %s", r.chownTo, strings.TrimSpace(string(stderr)))
+	}
+
+	return nil
+}
+
+// chownCommand returns the command changing the owner of the files of the
+// job, which all start with the job's name, in a container with the work
+// directory mounted at /workdir. Files are selected by not belonging to the
+// owner yet, as the image's user need not be root.
+func chownCommand(job Job, owner string) []string {
+	name := filepath.ToSlash(job.Name)
+	uid := strings.SplitN(owner, ":", 2)[0]
+	return []string{
+		"find", path.Join("/workdir", path.Dir(name)),
+		"-maxdepth", "1",
+		"-name", escapeGlob(path.Base(name)) + "*",
+		"!", "-user", uid,
+		"-exec", "chown", owner, "{}", "+",
+	}
+}
+
+// escapeGlob escapes the characters find -name treats as patterns.
+func escapeGlob(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+	return r.Replace(s)
+}
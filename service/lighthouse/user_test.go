@@ -0,0 +1,190 @@
+package lighthouse
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+// TestContainerUser checks which user the container runs as and whether
+// files are handed over afterwards.
+func TestContainerUser(t *testing.T) {
+	testCases := []struct {
+		setting       string
+		uid, gid      int
+		rootless      bool
+		expectedRunAs string
+		expectedChown string
+	}{
+		{UserAuto, 1000, 100, false, "1000:100", ""},
+		{"", 1000, 100, false, "1000:100", ""},
+		{UserAuto, 1000, 100, true, "", ""},
+		{UserAuto, 0, 0, false, "", ""},
+		{UserAuto, -1, -1, false, "", ""},
+		{UserImage, 1000, 100, false, "", "1000:100"},
+		{UserImage, 1000, 100, true, "", ""},
+		{"2000:2000", 1000, 100, false, "2000:2000", ""},
+		{"2000:2000", 1000, 100, true, "2000:2000", ""},
+	}
+
+	for _, tc := range testCases {
+		runAs, chown := containerUser(tc.setting, tc.uid, tc.gid, tc.rootless)
+		if runAs != tc.expectedRunAs || chown != tc.expectedChown {
+			t.Errorf("%q as %d:%d, rootless %t: expected %q and %q, got %q and %q", tc.setting, tc.uid, tc.gid, tc.rootless, tc.expectedRunAs, tc.expectedChown, runAs, chown)
+		}
+	}
+}
+
+// TestParseRootless checks the detection of rootless runtimes.
+func TestParseRootless(t *testing.T) {
+	testCases := []struct {
+		binary   string
+		info     string
+		expected bool
+	}{
+		{"docker", `["name=seccomp,profile=default"]` + "\n", false},
+		{"docker", `["name=seccomp,profile=default","name=rootless","name=cgroupns"]` + "\n", true},
+		{"podman", "false\n", false},
+		{"podman", "true\n", true},
+	}
+
+	for _, tc := range testCases {
+		rootless := parseRootless(tc.binary, tc.info)
+		if rootless != tc.expected {
+			t.Errorf("%s %q: expected %t, got %t", tc.binary, tc.info, tc.expected, rootless)
+		}
+	}
+}
+
+// TestValidateUserConfig checks the accepted container users.
+func TestValidateUserConfig(t *testing.T) {
+	testCases := []struct {
+		config RunnerConfig
+		valid  bool
+	}{
+		{RunnerConfig{}, true},
+		{RunnerConfig{User: UserAuto}, true},
+		{RunnerConfig{User: UserImage}, true},
+		{RunnerConfig{User: "1000"}, true},
+		{RunnerConfig{User: "1000:100"}, true},
+		{RunnerConfig{User: "node"}, false},
+		{RunnerConfig{User: "1000:"}, false},
+		{RunnerConfig{User: UserAuto, Runtime: RuntimeNative}, true},
+		{RunnerConfig{User: UserImage, Runtime: RuntimeNative}, false},
+	}
+
+	for _, tc := range testCases {
+		err := validateUserConfig(tc.config)
+		if tc.valid && err != nil {
+			t.Errorf("config %+v: unexpected error %s", tc.config, err)
+		} else if !tc.valid && !IsInvalidConfigError(err) {
+			t.Errorf("config %+v: expected invalid config error, got %v", tc.config, err)
+		}
+	}
+}
+
+// TestEscapeGlob checks that report names are matched literally by find.
+func TestEscapeGlob(t *testing.T) {
+	escaped := escapeGlob(`a*b?c[d]\e`)
+	expected := `a\*b\?c\[d]\\e`
+	if escaped != expected {
+		t.Errorf("expected %q, got %q", expected, escaped)
+	}
+}
+
+// TestChownCommand checks which files are handed over to the invoking user.
+func TestChownCommand(t *testing.T) {
+	testCases := []struct {
+		name     string
+		owner    string
+		expected []string
+	}{
+		{"index", "1000:100", []string{"find", "/workdir", "-maxdepth", "1", "-name", "index*", "!", "-user", "1000", "-exec", "chown", "1000:100", "{}", "+"}},
+		{"example.com/a*b", "501:20", []string{"find", "/workdir/example.com", "-maxdepth", "1", "-name", `a\*b*`, "!", "-user", "501", "-exec", "chown", "501:20", "{}", "+"}},
+	}
+
+	for _, tc := range testCases {
+		args := chownCommand(Job{Name: tc.name}, tc.owner)
+		if !reflect.DeepEqual(args, tc.expected) {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, args)
+		}
+	}
+}
+
+// TestSharedMemoryDir checks that containers running as another user can
+// write to /dev/shm.
+func TestSharedMemoryDir(t *testing.T) {
+	testCases := []struct {
+		runAs        string
+		expectedMode os.FileMode
+	}{
+		{"", 0700},
+		{"1234:1234", os.ModeSticky | 0777},
+	}
+
+	for _, tc := range testCases {
+		dir, err := sharedMemoryDir(tc.runAs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mode := info.Mode() & (os.ModePerm | os.ModeSticky)
+		if mode != tc.expectedMode {
+			t.Errorf("%q: expected mode %v, got %v", tc.runAs, tc.expectedMode, mode)
+		}
+	}
+}
+
+// TestHandOverSecrets checks that the extra headers are handed over to a
+// container user other than the invoking one, which only root may do.
+func TestHandOverSecrets(t *testing.T) {
+	own := fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+
+	testCases := []struct {
+		name          string
+		runAs         string
+		expectedOwner int
+		expectError   bool
+	}{
+		{name: "image user", runAs: "", expectedOwner: os.Getuid()},
+		{name: "invoking user", runAs: own, expectedOwner: os.Getuid()},
+		{name: "other user", runAs: "12345:12345", expectedOwner: 12345, expectError: os.Getuid() != 0},
+	}
+
+	for _, tc := range testCases {
+		dir, path, err := writeExtraHeaders(map[string]string{"Authorization": "Bearer secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = handOverSecrets([]string{dir}, tc.runAs)
+		if tc.expectError {
+			if !IsInvalidConfigError(err) {
+				t.Errorf("%s: expected invalid config error, got %v", tc.name, err)
+			}
+			os.RemoveAll(dir)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", tc.name, err)
+		}
+
+		for _, p := range []string{dir, path} {
+			info, err := os.Stat(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if uid := int(info.Sys().(*syscall.Stat_t).Uid); uid != tc.expectedOwner {
+				t.Errorf("%s: expected %s to belong to uid %d, got %d", tc.name, p, tc.expectedOwner, uid)
+			}
+		}
+		os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,157 @@
+package lighthouse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Defaults for waiting until the audit target is ready.
+const (
+	DefaultWaitTimeout  = 60 * time.Second
+	DefaultWaitInterval = 2 * time.Second
+)
+
+// httpProbeScript requests the URL given as first argument and prints the
+// status code in the first line, followed by the start of the body.
+const httpProbeScript = `
+const target = require('url').parse(process.argv[1]);
+const lib = require(target.protocol === 'https:' ? 'https' : 'http');
+target.rejectUnauthorized = false;
+target.timeout = 5000;
+const req = lib.get(target, (res) => {
+  let body = '';
+  res.setEncoding('utf8');
+  res.on('data', (chunk) => { if (body.length < 65536) { body += chunk; } });
+  res.on('end', () => { process.stdout.write(res.statusCode + '\n' + body); });
+});
+req.on('timeout', () => { req.abort(); });
+req.on('error', (err) => { process.stderr.write(err.message); process.exit(1); });
+`
+
+// tcpProbeScript connects to the host and port given as arguments.
+const tcpProbeScript = `
+const socket = require('net').connect({host: process.argv[1], port: parseInt(process.argv[2], 10)}, () => {
+  socket.end();
+  process.exit(0);
+});
+socket.setTimeout(5000, () => { process.stderr.write('timeout'); process.exit(1); });
+socket.on('error', (err) => { process.stderr.write(err.message); process.exit(1); });
+`
+
+// WaitConfig describes how to check that the audit target is ready.
+type WaitConfig struct {
+	// Target is either an http(s):// URL or a TCP address in the form
+	// tcp://host:port or host:port.
+	Target string
+	// Timeout is the maximum time to wait. Defaults to DefaultWaitTimeout.
+	Timeout time.Duration
+	// Interval is the delay between probes. Defaults to
+	// DefaultWaitInterval.
+	Interval time.Duration
+	// ExpectStatus is the HTTP status code expected. Zero accepts any
+	// status below 400.
+	ExpectStatus int
+	// ExpectBody is a substring the HTTP response body must contain.
+	ExpectBody string
+}
+
+// WaitForReady probes the target from within the lighthouse runtime, so it
+// sees the same network as lighthouse, until it answers as expected or the
+// timeout is reached.
+func WaitForReady(ctx context.Context, runner Runner, config WaitConfig, out io.Writer) error {
+	if config.Timeout == 0 {
+		config.Timeout = DefaultWaitTimeout
+	}
+	if config.Interval == 0 {
+		config.Interval = DefaultWaitInterval
+	}
+
+	args, isHTTP, err := probeArgs(config.Target)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	fmt.Fprintf(out, "Waiting up to %s for %s\n", config.Timeout, config.Target)
+
+	for attempt := 1; ; attempt++ {
+		var output []byte
+		output, err = runner.Exec(ctx, args)
+		if err == nil && isHTTP {
+			err = checkHTTPProbe(string(output), config.ExpectStatus, config.ExpectBody)
+		}
+		if err == nil {
+			fmt.Fprintf(out, "%s is ready\n", config.Target)
+			return nil
+		}
+
+		select {
+		case <-time.After(config.Interval):
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return microerror.Maskf(notReadyError, "%s not ready after %s and %d probes, last error: %s", config.Target, config.Timeout, attempt, err)
+			}
+			return microerror.Mask(ctx.Err())
+		}
+	}
+}
+
+// probeArgs returns the command line to probe the target and whether it is
+// an HTTP probe.
+func probeArgs(target string) ([]string, bool, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		_, err := url.Parse(target)
+		if err != nil {
+			return nil, false, microerror.Maskf(invalidConfigError, "invalid wait target %q: %s", target, err)
+		}
+		return []string{"node", "-e", httpProbeScript, target}, true, nil
+	}
+
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(target, "tcp://"))
+	if err != nil {
+		return nil, false, microerror.Maskf(invalidConfigError, "wait target %q must be an http(s) URL or a tcp://host:port address", target)
+	}
+	_, err = strconv.Atoi(port)
+	if err != nil {
+		return nil, false, microerror.Maskf(invalidConfigError, "invalid port in wait target %q", target)
+	}
+
+	return []string{"node", "-e", tcpProbeScript, host, port}, false, nil
+}
+
+// checkHTTPProbe checks the output of httpProbeScript against the
+// expectations.
+func checkHTTPProbe(output string, expectStatus int, expectBody string) error {
+	lines := strings.SplitN(output, "\n", 2)
+	status, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return microerror.Maskf(notReadyError, "unexpected probe output %q", output)
+	}
+
+	body := ""
+	if len(lines) > 1 {
+		body = lines[1]
+	}
+
+	if expectStatus != 0 && status != expectStatus {
+		return microerror.Maskf(notReadyError, "got status %d, expected %d", status, expectStatus)
+	}
+	if expectStatus == 0 && status >= 400 {
+		return microerror.Maskf(notReadyError, "got status %d", status)
+	}
+	if expectBody != "" && !strings.Contains(body, expectBody) {
+		return microerror.Maskf(notReadyError, "response body does not contain %q", expectBody)
+	}
+
+	return nil
+}
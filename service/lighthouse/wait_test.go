@@ -0,0 +1,66 @@
+package lighthouse
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// TestWaitForReady checks that probing continues until the target answers
+// as expected.
+func TestWaitForReady(t *testing.T) {
+	runner := newFakeRunner(t)
+	runner.execOutputs = []string{"", "502\nBad Gateway", "200\nstarting", "200\n<html>ready</html>"}
+
+	config := WaitConfig{
+		Target:     "http://site:8000/health",
+		Interval:   time.Millisecond,
+		ExpectBody: "ready",
+	}
+
+	err := WaitForReady(context.Background(), runner, config, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runner.execOutputs) != 0 {
+		t.Errorf("expected all probes to be used, %d left", len(runner.execOutputs))
+	}
+
+	runner.execOutputs = []string{"503\n", "503\n", "503\n", "503\n", "503\n"}
+	config.Timeout = 20 * time.Millisecond
+	config.Interval = 5 * time.Millisecond
+	err = WaitForReady(context.Background(), runner, config, ioutil.Discard)
+	if !IsNotReadyError(err) {
+		t.Errorf("expected notReadyError, got %v", err)
+	}
+}
+
+// TestProbeArgs checks the parsing of wait targets.
+func TestProbeArgs(t *testing.T) {
+	testCases := []struct {
+		target string
+		isHTTP bool
+		valid  bool
+	}{
+		{"http://site:8000/", true, true},
+		{"https://site/", true, true},
+		{"tcp://db:5432", false, true},
+		{"db:5432", false, true},
+		{"db", false, false},
+		{"tcp://db:port", false, false},
+	}
+
+	for _, tc := range testCases {
+		_, isHTTP, err := probeArgs(tc.target)
+		if tc.valid && err != nil {
+			t.Errorf("%q: unexpected error %s", tc.target, err)
+		}
+		if !tc.valid && !IsInvalidConfigError(err) {
+			t.Errorf("%q: expected invalidConfigError, got %v", tc.target, err)
+		}
+		if isHTTP != tc.isHTTP {
+			t.Errorf("%q: expected isHTTP %t", tc.target, tc.isHTTP)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package output
+
+import "github.com/giantswarm/microerror"
+
+// invalidTemplateError is used when a name template can't be parsed or
+// produces an unusable name
+var invalidTemplateError = &microerror.Error{
+	Kind: "invalidTemplateError",
+}
+
+// IsInvalidTemplateError asserts invalidTemplateError
+func IsInvalidTemplateError(err error) bool {
+	return microerror.Cause(err) == invalidTemplateError
+}
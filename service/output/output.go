@@ -0,0 +1,217 @@
+// Package output names the reports of an audit run and lists them in a
+// manifest.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/lighthouse-keeper/service/urllist"
+)
+
+// ManifestFile is the name of the manifest written to the output
+// directory.
+const ManifestFile = "lighthouse-keeper-manifest.json"
+
+// NameFields are the fields available in name templates.
+type NameFields struct {
+	// Name is the name given for the URL, if any.
+	Name string
+	// Host is the host name of the URL, with a port separated by a dash.
+	Host string
+	// PathSlug is the URL path and query in lower case with other
+	// characters than letters and digits replaced by dashes, or "index"
+	// for the root path, like names of listed URLs.
+	PathSlug string
+	// FormFactor is the emulated form factor.
+	FormFactor string
+	// Preset is the emulation preset, if any.
+	Preset string
+	// Index is the position of the URL in the audit run, starting at 1.
+	Index int
+	// Date is the start date of the run as YYYYMMDD.
+	Date string
+	// Time is the start time of the run as HHMMSS.
+	Time string
+	// GitSHA is the abbreviated commit of the working directory's git
+	// repository, or "unknown".
+	GitSHA string
+}
+
+// NewNameFields returns the fields of a URL in a run started at start.
+func NewNameFields(rawURL string, start time.Time, gitSHA string) NameFields {
+	fields := NameFields{
+		PathSlug: urllist.Slug(rawURL),
+		Date:     start.Format("20060102"),
+		Time:     start.Format("150405"),
+		GitSHA:   gitSHA,
+	}
+
+	u, err := url.Parse(rawURL)
+	if err == nil {
+		fields.Host = strings.Replace(strings.ToLower(u.Host), ":", "-", -1)
+	}
+
+	return fields
+}
+
+// AutoName returns the name of the URL at index of a run started at start,
+// for URLs given without a name. Like given names, it gets the form factor
+// appended, so reports of a URL can be paired by form factor.
+func AutoName(start time.Time, index int) string {
+	return fmt.Sprintf("%s-%d", start.Format("20060102-150405"), index+1)
+}
+
+// GitSHA returns the abbreviated commit checked out in the working
+// directory, falling back to the commit given by common CI environment
+// variables, or "unknown".
+func GitSHA() string {
+	out, err := exec.Command("git", "rev-parse", "--short=7", "HEAD").Output()
+	if err == nil {
+		return strings.TrimSpace(string(out))
+	}
+
+	for _, env := range []string{"GITHUB_SHA", "CI_COMMIT_SHA", "CIRCLE_SHA1", "GIT_COMMIT"} {
+		if sha := os.Getenv(env); len(sha) >= 7 {
+			return sha[:7]
+		}
+	}
+
+	return "unknown"
+}
+
+// NameTemplate renders report names from NameFields.
+type NameTemplate struct {
+	template *template.Template
+}
+
+// ParseNameTemplate parses a text/template name template, e.g.
+// '{{.Host}}-{{.PathSlug}}-{{.FormFactor}}'.
+func ParseNameTemplate(text string) (*NameTemplate, error) {
+	t, err := template.New("name").Parse(text)
+	if err != nil {
+		return nil, microerror.Maskf(invalidTemplateError, "%s", err)
+	}
+
+	// render with example values to find unknown fields early
+	err = t.Execute(ioutil.Discard, NewNameFields("https://example.com/", time.Now(), "0000000"))
+	if err != nil {
+		return nil, microerror.Maskf(invalidTemplateError, "%s", err)
+	}
+
+	return &NameTemplate{template: t}, nil
+}
+
+// Execute renders a name. Names may contain slashes for subdirectories,
+// but must stay within the output directory.
+func (t *NameTemplate) Execute(fields NameFields) (string, error) {
+	var buf bytes.Buffer
+	err := t.template.Execute(&buf, fields)
+	if err != nil {
+		return "", microerror.Maskf(invalidTemplateError, "%s", err)
+	}
+
+	name := strings.TrimSpace(buf.String())
+	clean := path.Clean(name)
+	if name == "" || clean == "." || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", microerror.Maskf(invalidTemplateError, "name %q must be a relative path within the output directory", name)
+	}
+
+	return clean, nil
+}
+
+// Distinguishes tells whether the template renders different names for two
+// values of a field, set by the given function.
+func (t *NameTemplate) Distinguishes(set func(fields *NameFields, value string), a, b string) bool {
+	fields := NewNameFields("https://example.com/", time.Now(), "0000000")
+	set(&fields, a)
+	nameA, errA := t.Execute(fields)
+	set(&fields, b)
+	nameB, errB := t.Execute(fields)
+
+	return errA == nil && errB == nil && nameA != nameB
+}
+
+// Namer makes report names unique.
+type Namer struct {
+	dir  string
+	used map[string]bool
+}
+
+// NewNamer returns a Namer for reports in dir. The name of the manifest is
+// reserved.
+func NewNamer(dir string) *Namer {
+	used := map[string]bool{
+		strings.TrimSuffix(ManifestFile, ".json"): true,
+	}
+
+	return &Namer{dir: dir, used: used}
+}
+
+// Unique returns the name, with a counter appended if it was used before
+// in this run. If keepExisting is set, names of reports already in the
+// output directory are not used either, so they aren't overwritten.
+func (n *Namer) Unique(name string, keepExisting bool) string {
+	unique := name
+	for i := 2; n.used[unique] || (keepExisting && n.exists(unique)); i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	n.used[unique] = true
+
+	return unique
+}
+
+func (n *Namer) exists(name string) bool {
+	_, err := os.Stat(filepath.Join(n.dir, filepath.FromSlash(name)+".json"))
+	return err == nil
+}
+
+// ManifestEntry describes a report of the run. Paths are relative to the
+// output directory.
+type ManifestEntry struct {
+	URL        string `json:"url"`
+	Name       string `json:"name"`
+	FormFactor string `json:"formFactor"`
+	Preset     string `json:"preset,omitempty"`
+	Report     string `json:"report,omitempty"`
+	HTML       string `json:"html,omitempty"`
+	CSV        string `json:"csv,omitempty"`
+	Metadata   string `json:"metadata,omitempty"`
+	Summary    string `json:"summary,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Manifest lists the reports written in a run.
+type Manifest struct {
+	Started time.Time       `json:"started"`
+	GitSHA  string          `json:"gitSHA,omitempty"`
+	Reports []ManifestEntry `json:"reports"`
+}
+
+// WriteManifest writes the manifest to ManifestFile in dir.
+func WriteManifest(dir string, manifest Manifest) (string, error) {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	path := filepath.Join(dir, ManifestFile)
+	err = ioutil.WriteFile(path, append(data, '\n'), 0644)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,115 @@
+package output
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestNameTemplate checks rendering names from URLs.
+func TestNameTemplate(t *testing.T) {
+	start := time.Date(2019, 3, 14, 15, 9, 26, 0, time.UTC)
+
+	testCases := []struct {
+		template    string
+		url         string
+		expected    string
+		expectError bool
+	}{
+		{template: "{{.Host}}-{{.PathSlug}}-{{.FormFactor}}", url: "https://Example.com/blog/Hello/", expected: "example.com-blog-hello-mobile"},
+		{template: "{{.Host}}/{{.PathSlug}}", url: "http://localhost:3000/", expected: "localhost-3000/index"},
+		{template: "{{.Date}}-{{.Time}}-{{.GitSHA}}-{{.Index}}", url: "https://example.com/", expected: "20190314-150926-abc1234-3"},
+		{template: "{{.Nmae}}", url: "https://example.com/", expectError: true},
+		{template: "../{{.PathSlug}}", url: "https://example.com/", expectError: true},
+		{template: "/tmp/{{.PathSlug}}", url: "https://example.com/", expectError: true},
+		{template: "{{if .Name}}{{.Name}}{{end}}", url: "https://example.com/", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		nt, err := ParseNameTemplate(tc.template)
+		if err == nil {
+			fields := NewNameFields(tc.url, start, "abc1234")
+			fields.FormFactor = "mobile"
+			fields.Index = 3
+			var name string
+			name, err = nt.Execute(fields)
+			if err == nil && name != tc.expected {
+				t.Errorf("%s: expected %q, got %q", tc.template, tc.expected, name)
+			}
+		}
+		if tc.expectError && !IsInvalidTemplateError(err) {
+			t.Errorf("%s: expected invalid template error, got %v", tc.template, err)
+		} else if !tc.expectError && err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.template, err)
+		}
+	}
+
+	nt, err := ParseNameTemplate("{{.Host}}-{{.Preset}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setPreset := func(f *NameFields, v string) { f.Preset = v }
+	setFormFactor := func(f *NameFields, v string) { f.FormFactor = v }
+	if !nt.Distinguishes(setPreset, "no-throttling", "desktop-cable") {
+		t.Errorf("expected presets to be told apart")
+	}
+	if nt.Distinguishes(setFormFactor, "desktop", "mobile") {
+		t.Errorf("expected form factors not to be told apart")
+	}
+}
+
+// TestAutoName checks the names of URLs given without a name, which must
+// not contain the form factor before it is appended.
+func TestAutoName(t *testing.T) {
+	start := time.Date(2019, 3, 14, 15, 9, 26, 0, time.UTC)
+
+	testCases := []struct {
+		index    int
+		expected string
+	}{
+		{0, "20190314-150926-1"},
+		{11, "20190314-150926-12"},
+	}
+
+	for _, tc := range testCases {
+		name := AutoName(start, tc.index)
+		if name != tc.expected {
+			t.Errorf("%d: expected %q, got %q", tc.index, tc.expected, name)
+		}
+	}
+}
+
+// TestNamer checks that names are unique within a run and optionally
+// don't overwrite existing reports.
+func TestNamer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "output-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "existing.json"), []byte("{}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	namer := NewNamer(dir)
+	names := []string{
+		namer.Unique("home", true),
+		namer.Unique("home", true),
+		namer.Unique("existing", true),
+		namer.Unique("existing", false),
+		namer.Unique("home", false),
+		namer.Unique("lighthouse-keeper-manifest", false),
+	}
+
+	expected := []string{"home", "home-2", "existing-2", "existing", "home-3", "lighthouse-keeper-manifest-2"}
+	for i := range names {
+		if names[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, names)
+			break
+		}
+	}
+}
@@ -26,6 +26,8 @@ type Report struct {
 	FinalURL          string    `json:"finalUrl"`
 	RequestedURL      string    `json:"requestedUrl"`
 
+	RuntimeError *RuntimeError `json:"runtimeError"`
+
 	Audits map[string]Audit `json:"audits"`
 
 	Categories map[string]Category `json:"categories"`
@@ -42,6 +44,12 @@ type Audit struct {
 	DisplayValue     DisplayValue     `json:"displayValue"`
 }
 
+// RuntimeError is set by lighthouse if the page could not be audited
+type RuntimeError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type Category struct {
 	ID        string     `json:"id"`
 	Title     string     `json:"title"`
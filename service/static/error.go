@@ -0,0 +1,13 @@
+package static
+
+import "github.com/giantswarm/microerror"
+
+// invalidConfigError is used when the server configuration is invalid
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfigError asserts invalidConfigError
+func IsInvalidConfigError(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
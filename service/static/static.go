@@ -0,0 +1,275 @@
+// Package static serves a directory of static files, like the build output
+// of a single page application, for auditing.
+package static
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+)
+
+// maxLogLines is the number of request log lines kept.
+const maxLogLines = 1000
+
+// Config describes a static file server.
+type Config struct {
+	// Dir is the directory to serve.
+	Dir string
+	// Address to listen on, e.g. 127.0.0.1:0 for a random port on the
+	// loopback interface.
+	Address string
+	// ExtraIPs are further IP addresses to listen on, with the port of
+	// Address.
+	ExtraIPs []string
+}
+
+// Server serves a directory over HTTP.
+type Server struct {
+	dir       string
+	listeners []net.Listener
+	server    *http.Server
+
+	mutex sync.Mutex
+	log   []string
+}
+
+// Start starts serving the directory in the background.
+func Start(config Config) (*Server, error) {
+	info, err := os.Stat(config.Dir)
+	if err != nil || !info.IsDir() {
+		return nil, microerror.Maskf(invalidConfigError, "%q is not a directory", config.Dir)
+	}
+
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	listeners := []net.Listener{listener}
+
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	for _, ip := range config.ExtraIPs {
+		listener, err = net.Listen("tcp", net.JoinHostPort(ip, port))
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, microerror.Mask(err)
+		}
+		listeners = append(listeners, listener)
+	}
+
+	s := &Server{dir: config.Dir, listeners: listeners}
+	s.server = &http.Server{Handler: s.logRequests(Handler(config.Dir))}
+	for _, l := range listeners {
+		go s.server.Serve(l)
+	}
+
+	return s, nil
+}
+
+// Port returns the port the server listens on.
+func (s *Server) Port() int {
+	return s.listeners[0].Addr().(*net.TCPAddr).Port
+}
+
+// Log returns the most recent requests with their status.
+func (s *Server) Log() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return strings.Join(s.log, "\n")
+}
+
+// Stop stops the server, waiting for active requests until ctx is done.
+func (s *Server) Stop(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (s *Server) logRequests(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.log = append(s.log, fmt.Sprintf("%s %s %d", r.Method, r.URL.RequestURI(), recorder.status))
+		if len(s.log) > maxLogLines {
+			s.log = s.log[len(s.log)-maxLogLines:]
+		}
+	})
+}
+
+// Handler serves the files in dir. Directories are served by their
+// index.html. Paths without a file extension that don't exist are served by
+// the root index.html, so client side routes of single page applications
+// work. Text content is gzip compressed if the client accepts it.
+func Handler(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		file := resolve(dir, r.URL.Path)
+		if file == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		serveFile(w, r, file)
+	})
+}
+
+// resolve returns the file to serve for the URL path, or an empty string.
+func resolve(dir, urlPath string) string {
+	// path.Clean of a rooted path never leaves the root
+	name := filepath.Join(dir, filepath.FromSlash(path.Clean("/"+urlPath)))
+
+	info, err := os.Stat(name)
+	if err == nil && info.IsDir() {
+		name = filepath.Join(name, "index.html")
+		info, err = os.Stat(name)
+	}
+	if err == nil && !info.IsDir() {
+		return name
+	}
+
+	if path.Ext(urlPath) == "" {
+		index := filepath.Join(dir, "index.html")
+		if info, err := os.Stat(index); err == nil && !info.IsDir() {
+			return index
+		}
+	}
+
+	return ""
+}
+
+func serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	f, err := os.Open(name)
+	if err != nil {
+		http.Error(w, "could not open file", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if !compressible(contentType) || !acceptsGzip(r) {
+		if info, err := f.Stat(); err == nil {
+			w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+		}
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			io.Copy(w, f)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	gz := gzip.NewWriter(w)
+	io.Copy(gz, f)
+	gz.Close()
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "javascript"),
+		strings.HasSuffix(mediaType, "json"),
+		strings.HasSuffix(mediaType, "xml"),
+		mediaType == "image/svg+xml":
+		return true
+	}
+
+	return false
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, e := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(e, ";")
+		if strings.TrimSpace(parts[0]) != "gzip" {
+			continue
+		}
+		for _, p := range parts[1:] {
+			p = strings.Replace(p, " ", "", -1)
+			if strings.HasPrefix(p, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(p, "q="), 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// HTMLPaths returns the URL paths of all HTML files in dir, sorted. Files
+// named index.html are listed by their directory path.
+func HTMLPaths(dir string) ([]string, error) {
+	paths := []string{}
+	err := filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(name), ".html") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		p := "/" + filepath.ToSlash(rel)
+		if path.Base(p) == "index.html" {
+			p = strings.TrimSuffix(p, "index.html")
+		}
+		paths = append(paths, p)
+
+		return nil
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
@@ -0,0 +1,172 @@
+package static
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newDir creates a directory with the given files and contents.
+func newDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "static-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// TestHandler checks file resolution, the SPA fallback and compression.
+func TestHandler(t *testing.T) {
+	dir := newDir(t, map[string]string{
+		"index.html":       "root",
+		"about/index.html": "about",
+		"app.js":           "script",
+		"logo.png":         "png",
+	})
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		path           string
+		acceptEncoding string
+		expectedStatus int
+		expectedBody   string
+		expectedGzip   bool
+	}{
+		{path: "/", expectedStatus: http.StatusOK, expectedBody: "root"},
+		{path: "/about", expectedStatus: http.StatusOK, expectedBody: "about"},
+		{path: "/about/", expectedStatus: http.StatusOK, expectedBody: "about"},
+		{path: "/app.js", expectedStatus: http.StatusOK, expectedBody: "script"},
+		{path: "/app.js", acceptEncoding: "br, gzip", expectedStatus: http.StatusOK, expectedBody: "script", expectedGzip: true},
+		{path: "/app.js", acceptEncoding: "gzip;q=0", expectedStatus: http.StatusOK, expectedBody: "script"},
+		{path: "/logo.png", acceptEncoding: "gzip", expectedStatus: http.StatusOK, expectedBody: "png"},
+		{path: "/users/42/settings", expectedStatus: http.StatusOK, expectedBody: "root"},
+		{path: "/missing.css", expectedStatus: http.StatusNotFound},
+		{path: "/../../etc/passwd.txt", expectedStatus: http.StatusNotFound},
+	}
+
+	handler := Handler(dir)
+	for _, tc := range testCases {
+		request := httptest.NewRequest(http.MethodGet, "http://site"+tc.path, nil)
+		if tc.acceptEncoding != "" {
+			request.Header.Set("Accept-Encoding", tc.acceptEncoding)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != tc.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", tc.path, tc.expectedStatus, recorder.Code)
+			continue
+		}
+		if tc.expectedStatus != http.StatusOK {
+			continue
+		}
+
+		gzipped := recorder.Header().Get("Content-Encoding") == "gzip"
+		if gzipped != tc.expectedGzip {
+			t.Errorf("%s %q: expected gzip %t, got %t", tc.path, tc.acceptEncoding, tc.expectedGzip, gzipped)
+		}
+
+		body := recorder.Body.String()
+		if gzipped {
+			reader, err := gzip.NewReader(recorder.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = string(data)
+		}
+		if body != tc.expectedBody {
+			t.Errorf("%s: expected body %q, got %q", tc.path, tc.expectedBody, body)
+		}
+	}
+}
+
+// TestServer checks serving over the network and the request log.
+func TestServer(t *testing.T) {
+	dir := newDir(t, map[string]string{"index.html": "root"})
+	defer os.RemoveAll(dir)
+
+	_, err := Start(Config{Dir: filepath.Join(dir, "index.html")})
+	if !IsInvalidConfigError(err) {
+		t.Errorf("expected invalid config error, got %v", err)
+	}
+
+	server, err := Start(Config{Dir: dir, Address: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/missing.js", server.Port()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if server.Log() != "GET /missing.js 404" {
+		t.Errorf("unexpected log %q", server.Log())
+	}
+	server.Stop(context.Background())
+
+	// another loopback address stands in for the docker bridge gateway
+	server, err = Start(Config{Dir: dir, Address: "127.0.0.1:0", ExtraIPs: []string{"127.0.0.2"}})
+	if err != nil {
+		t.Skipf("could not listen on a second loopback address: %s", err)
+	}
+	defer server.Stop(context.Background())
+
+	for _, ip := range []string{"127.0.0.1", "127.0.0.2"} {
+		response, err = http.Get(fmt.Sprintf("http://%s:%d/", ip, server.Port()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", ip, response.StatusCode)
+		}
+	}
+}
+
+// TestHTMLPaths checks listing the pages of a directory.
+func TestHTMLPaths(t *testing.T) {
+	dir := newDir(t, map[string]string{
+		"index.html":         "",
+		"about/index.html":   "",
+		"blog/post.html":     "",
+		"assets/app.js":      "",
+		"assets/partial.htm": "",
+	})
+	defer os.RemoveAll(dir)
+
+	paths, err := HTMLPaths(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"/", "/about/", "/blog/post.html"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+}
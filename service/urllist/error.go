@@ -0,0 +1,23 @@
+package urllist
+
+import "github.com/giantswarm/microerror"
+
+// invalidFileError is used when a URL file can't be parsed
+var invalidFileError = &microerror.Error{
+	Kind: "invalidFileError",
+}
+
+// IsInvalidFileError asserts invalidFileError
+func IsInvalidFileError(err error) bool {
+	return microerror.Cause(err) == invalidFileError
+}
+
+// sitemapError is used when a sitemap can't be fetched or parsed
+var sitemapError = &microerror.Error{
+	Kind: "sitemapError",
+}
+
+// IsSitemapError asserts sitemapError
+func IsSitemapError(err error) bool {
+	return microerror.Cause(err) == sitemapError
+}
@@ -0,0 +1,139 @@
+package urllist
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// maxSitemapDepth limits how deep sitemap indexes are followed. The
+// protocol doesn't allow nested indexes, one extra level is tolerated.
+const maxSitemapDepth = 3
+
+// maxSitemapSize is the maximum size of an uncompressed sitemap according
+// to the sitemap protocol.
+const maxSitemapSize = 50 * 1024 * 1024
+
+type sitemapDocument struct {
+	XMLName xml.Name
+	URLs    []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// ReadSitemap fetches a sitemap or a sitemap index and returns the page
+// URLs it lists, in order and without duplicates. The sitemaps of an index
+// are fetched in turn. Gzip compressed sitemaps are supported.
+func ReadSitemap(ctx context.Context, client *http.Client, sitemapURL string) ([]Entry, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	entries := []Entry{}
+	seen := map[string]bool{}
+	err := readSitemap(ctx, client, sitemapURL, 1, &entries, seen)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return entries, nil
+}
+
+func readSitemap(ctx context.Context, client *http.Client, sitemapURL string, depth int, entries *[]Entry, seen map[string]bool) error {
+	if depth > maxSitemapDepth {
+		return microerror.Maskf(sitemapError, "%s: sitemap indexes nested too deep", sitemapURL)
+	}
+
+	data, err := fetchSitemap(ctx, client, sitemapURL)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var doc sitemapDocument
+	err = xml.Unmarshal(data, &doc)
+	if err != nil {
+		return microerror.Maskf(sitemapError, "%s: %s", sitemapURL, err)
+	}
+
+	switch doc.XMLName.Local {
+	case "urlset":
+		for _, u := range doc.URLs {
+			loc := strings.TrimSpace(u.Loc)
+			if loc == "" || seen[loc] {
+				continue
+			}
+			seen[loc] = true
+			*entries = append(*entries, Entry{URL: loc})
+		}
+	case "sitemapindex":
+		for _, s := range doc.Sitemaps {
+			err := readSitemap(ctx, client, strings.TrimSpace(s.Loc), depth+1, entries, seen)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+	default:
+		return microerror.Maskf(sitemapError, "%s: expected urlset or sitemapindex, got %s", sitemapURL, doc.XMLName.Local)
+	}
+
+	return nil
+}
+
+func fetchSitemap(ctx context.Context, client *http.Client, sitemapURL string) ([]byte, error) {
+	request, err := http.NewRequest(http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, microerror.Maskf(sitemapError, "%s: %s", sitemapURL, err)
+	}
+
+	response, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, microerror.Maskf(sitemapError, "%s", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, microerror.Maskf(sitemapError, "%s: got status %s", sitemapURL, response.Status)
+	}
+
+	data, err := readLimited(response.Body)
+	if err != nil {
+		return nil, microerror.Maskf(sitemapError, "%s: %s", sitemapURL, err)
+	}
+
+	// .xml.gz files are usually served without Content-Encoding
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, microerror.Maskf(sitemapError, "%s: %s", sitemapURL, err)
+		}
+		data, err = readLimited(reader)
+		if err != nil {
+			return nil, microerror.Maskf(sitemapError, "%s: %s", sitemapURL, err)
+		}
+	}
+
+	return data, nil
+}
+
+// readLimited reads at most maxSitemapSize bytes.
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxSitemapSize+1))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if len(data) > maxSitemapSize {
+		return nil, microerror.Newf("sitemap is larger than %d bytes", maxSitemapSize)
+	}
+
+	return data, nil
+}
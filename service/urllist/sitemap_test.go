@@ -0,0 +1,63 @@
+package urllist
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestReadSitemap checks reading a sitemap index with a plain and a gzip
+// compressed sitemap.
+func TestReadSitemap(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/pages.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/blog.xml.gz</loc></sitemap>
+</sitemapindex>`, server.URL)
+	})
+	mux.HandleFunc("/pages.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc></url>
+  <url><loc> https://example.com/about </loc><lastmod>2019-01-01</lastmod></url>
+</urlset>`)
+	})
+	mux.HandleFunc("/blog.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		fmt.Fprint(gz, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/blog/1</loc></url>
+  <url><loc>https://example.com/about</loc></url>
+</urlset>`)
+		gz.Close()
+		w.Write(buf.Bytes())
+	})
+
+	entries, err := ReadSitemap(context.Background(), server.Client(), server.URL+"/sitemap.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"https://example.com/", "https://example.com/about", "https://example.com/blog/1"}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, entries)
+	}
+	for i, e := range entries {
+		if e.URL != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], e.URL)
+		}
+	}
+
+	_, err = ReadSitemap(context.Background(), server.Client(), server.URL+"/missing.xml")
+	if !IsSitemapError(err) {
+		t.Errorf("expected sitemap error, got %v", err)
+	}
+}
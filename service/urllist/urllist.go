@@ -0,0 +1,247 @@
+// Package urllist reads the URLs to audit from files and sitemaps and
+// derives report names from them.
+package urllist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+)
+
+// Entry is a URL to audit with an optional report name.
+type Entry struct {
+	URL  string
+	Name string
+}
+
+// ReadFile reads URLs from a file. Files ending in .csv have the columns
+// url and name, a header row is optional. Files ending in .yaml or .yml
+// contain a list of URLs or of mappings with url and name. Other files have
+// one URL per line, optionally followed by whitespace and a name. Empty
+// lines and lines starting with # are skipped.
+func ReadFile(path string) ([]Entry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var entries []Entry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		entries, err = parseCSV(data)
+	case ".yaml", ".yml":
+		entries, err = parseYAML(data)
+	default:
+		entries, err = parseLines(data)
+	}
+	if err != nil {
+		return nil, microerror.Maskf(err, "%s", path)
+	}
+
+	return entries, nil
+}
+
+func parseCSV(data []byte) ([]Entry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	entries := []Entry{}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, microerror.Maskf(invalidFileError, "%s", err)
+		}
+
+		if len(record) > 2 {
+			return nil, microerror.Maskf(invalidFileError, "row %d: expected url and optional name, got %d columns", row, len(record))
+		}
+		if len(entries) == 0 && strings.EqualFold(record[0], "url") {
+			continue
+		}
+
+		entry := Entry{URL: strings.TrimSpace(record[0])}
+		if len(record) == 2 {
+			entry.Name = strings.TrimSpace(record[1])
+		}
+		if entry.URL == "" {
+			return nil, microerror.Maskf(invalidFileError, "row %d: empty url", row)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func parseYAML(data []byte) ([]Entry, error) {
+	var document yaml.Node
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, microerror.Maskf(invalidFileError, "%s", strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	// an empty file has no content
+	if len(document.Content) == 0 {
+		return []Entry{}, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.SequenceNode {
+		return nil, microerror.Maskf(invalidFileError, "line %d: expected a list of URLs", root.Line)
+	}
+
+	entries := []Entry{}
+	for _, item := range root.Content {
+		if item.Kind == yaml.AliasNode {
+			item = item.Alias
+		}
+
+		switch item.Kind {
+		case yaml.ScalarNode:
+			entries = append(entries, Entry{URL: item.Value})
+		case yaml.MappingNode:
+			entry := Entry{}
+			for i := 0; i+1 < len(item.Content); i += 2 {
+				key, value := item.Content[i], item.Content[i+1]
+				if value.Kind != yaml.ScalarNode {
+					return nil, microerror.Maskf(invalidFileError, "line %d: %s must be a value", key.Line, key.Value)
+				}
+				switch key.Value {
+				case "url":
+					entry.URL = value.Value
+				case "name":
+					entry.Name = value.Value
+				default:
+					return nil, microerror.Maskf(invalidFileError, "line %d: unknown key %q, expected url or name", key.Line, key.Value)
+				}
+			}
+			if entry.URL == "" {
+				return nil, microerror.Maskf(invalidFileError, "line %d: missing url", item.Line)
+			}
+			entries = append(entries, entry)
+		default:
+			return nil, microerror.Maskf(invalidFileError, "line %d: expected a URL or a mapping with url and name", item.Line)
+		}
+	}
+
+	return entries, nil
+}
+
+func parseLines(data []byte) ([]Entry, error) {
+	entries := []Entry{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, microerror.Maskf(invalidFileError, "line %d: expected url and optional name, got %d fields", line, len(fields))
+		}
+
+		entry := Entry{URL: fields[0]}
+		if len(fields) == 2 {
+			entry.Name = fields[1]
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return entries, nil
+}
+
+// Filter returns the entries whose URL matches include, if given, and
+// doesn't match exclude, if given.
+func Filter(entries []Entry, include, exclude *regexp.Regexp) []Entry {
+	filtered := []Entry{}
+	for _, e := range entries {
+		if include != nil && !include.MatchString(e.URL) {
+			continue
+		}
+		if exclude != nil && exclude.MatchString(e.URL) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+
+	return filtered
+}
+
+// Sample returns at most max entries, evenly spread over the list so the
+// sample covers all of it. The first entry is always kept. The selection is
+// stable, so repeated audits use the same URLs. A max of zero keeps all
+// entries.
+func Sample(entries []Entry, max int) []Entry {
+	if max <= 0 || len(entries) <= max {
+		return entries
+	}
+
+	sampled := []Entry{}
+	for i := 0; i < max; i++ {
+		sampled = append(sampled, entries[i*len(entries)/max])
+	}
+
+	return sampled
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slug returns a report name derived from the URL's path and query, e.g.
+// "blog-2019-hello" for https://example.com/blog/2019/hello/. The root
+// path is named "index".
+func Slug(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return slugify(rawURL)
+	}
+
+	slug := slugify(u.Path)
+	if u.RawQuery != "" {
+		slug = strings.Trim(slug+"-"+slugify(u.RawQuery), "-")
+	}
+	if slug == "" {
+		slug = "index"
+	}
+
+	return slug
+}
+
+func slugify(s string) string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// Name sets missing names from the URL slugs and makes all names unique by
+// appending a counter to repeated ones.
+func Name(entries []Entry) []Entry {
+	named := []Entry{}
+	used := map[string]bool{}
+	for _, e := range entries {
+		if e.Name == "" {
+			e.Name = Slug(e.URL)
+		}
+		name := e.Name
+		for i := 2; used[name]; i++ {
+			name = e.Name + "-" + strconv.Itoa(i)
+		}
+		used[name] = true
+		e.Name = name
+		named = append(named, e)
+	}
+
+	return named
+}
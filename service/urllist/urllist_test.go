@@ -0,0 +1,136 @@
+package urllist
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestReadFile checks the supported URL file formats.
+func TestReadFile(t *testing.T) {
+	testCases := []struct {
+		file        string
+		content     string
+		expected    []Entry
+		expectError string
+	}{
+		{
+			file:    "urls.txt",
+			content: "# pages\nhttps://example.com/ home\n\n  https://example.com/about\n",
+			expected: []Entry{
+				{URL: "https://example.com/", Name: "home"},
+				{URL: "https://example.com/about"},
+			},
+		},
+		{
+			file:        "broken.txt",
+			content:     "https://example.com/ home page\n",
+			expectError: "line 1: expected url and optional name, got 3 fields",
+		},
+		{
+			file:    "urls.csv",
+			content: "url,name\nhttps://example.com/,home\n\"https://example.com/a,b\"\n",
+			expected: []Entry{
+				{URL: "https://example.com/", Name: "home"},
+				{URL: "https://example.com/a,b"},
+			},
+		},
+		{
+			file:        "broken.csv",
+			content:     "https://example.com/,home,extra\n",
+			expectError: "row 1: expected url and optional name, got 3 columns",
+		},
+		{
+			file:    "urls.yaml",
+			content: "- https://example.com/\n- url: https://example.com/about\n  name: about\n",
+			expected: []Entry{
+				{URL: "https://example.com/"},
+				{URL: "https://example.com/about", Name: "about"},
+			},
+		},
+		{
+			file:        "broken.yml",
+			content:     "- url: https://example.com/\n- nmae: about\n",
+			expectError: `line 2: unknown key "nmae", expected url or name`,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "urllist-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range testCases {
+		path := filepath.Join(dir, tc.file)
+		err := ioutil.WriteFile(path, []byte(tc.content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := ReadFile(path)
+		if tc.expectError != "" {
+			if !IsInvalidFileError(err) || !strings.Contains(err.Error(), tc.expectError) {
+				t.Errorf("%s: expected error %q, got %v", tc.file, tc.expectError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(entries, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.file, tc.expected, entries)
+		}
+	}
+}
+
+// TestFilterAndSample checks filtering by regular expressions and the
+// stable sampling.
+func TestFilterAndSample(t *testing.T) {
+	entries := []Entry{}
+	for _, p := range []string{"/", "/blog/1", "/blog/2", "/about", "/blog/3", "/jobs", "/blog/4", "/contact"} {
+		entries = append(entries, Entry{URL: "https://example.com" + p})
+	}
+
+	filtered := Filter(entries, regexp.MustCompile(`/blog/`), regexp.MustCompile(`/2$`))
+	if len(filtered) != 3 || filtered[0].URL != "https://example.com/blog/1" || filtered[2].URL != "https://example.com/blog/4" {
+		t.Errorf("unexpected filter result %v", filtered)
+	}
+
+	sampled := Sample(entries, 3)
+	expected := []string{"https://example.com/", "https://example.com/blog/2", "https://example.com/jobs"}
+	for i, e := range sampled {
+		if i >= len(expected) || e.URL != expected[i] {
+			t.Errorf("expected sample %v, got %v", expected, sampled)
+			break
+		}
+	}
+
+	if len(Sample(entries, 0)) != len(entries) {
+		t.Errorf("expected max 0 to keep all entries")
+	}
+}
+
+// TestName checks names derived from URLs and their deduplication.
+func TestName(t *testing.T) {
+	entries := []Entry{
+		{URL: "https://example.com/"},
+		{URL: "https://example.com/Blog/2019/Hello_World/"},
+		{URL: "https://example.com/search?q=shoes"},
+		{URL: "https://example.com/about", Name: "about"},
+		{URL: "https://example.com/about/"},
+		{URL: "https://example.org/about"},
+	}
+
+	expected := []string{"index", "blog-2019-hello-world", "search-q-shoes", "about", "about-2", "about-3"}
+	for i, e := range Name(entries) {
+		if e.Name != expected[i] {
+			t.Errorf("%s: expected name %q, got %q", e.URL, expected[i], e.Name)
+		}
+	}
+}